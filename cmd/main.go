@@ -43,10 +43,10 @@ func main() {
 	defer store.Close()
 
 	// 使用配置初始化区块链
-	bc := blockchain.NewBlockchain(store, cfg.Blockchain.NodeAddress, cfg.Server.Port)
-    if bc == nil {
-        log.Fatal("Failed to initialize blockchain")
-    }
+	bc := blockchain.NewBlockchain(store, cfg)
+	if bc == nil {
+		log.Fatal("Failed to initialize blockchain")
+	}
 	log.Println("Blockchain initialized successfully")
 
 	// 启动服务器,使用配置的主机和端口
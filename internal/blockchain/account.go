@@ -0,0 +1,38 @@
+package blockchain
+
+import (
+	"fmt"
+
+	"twichain/internal/crypto"
+)
+
+// AccountNonce 账户的交易序号，客户端用 NextNonce 签名下一笔交易
+type AccountNonce struct {
+	Address        string `json:"address"`
+	ConfirmedNonce uint64 `json:"confirmed_nonce"` // 已上链的最新序号
+	PendingNonce   uint64 `json:"pending_nonce"`   // 交易池中的最大序号，没有待打包交易时为 0
+	NextNonce      uint64 `json:"next_nonce"`
+}
+
+// GetAccountNonce 从存储的序号索引和交易池查询账户的交易序号
+func (bc *Blockchain) GetAccountNonce(address string) (*AccountNonce, error) {
+	if !crypto.ValidateAddress(address) {
+		return nil, fmt.Errorf("invalid address format - must be 256-bit hex string")
+	}
+
+	bc.mu.RLock()
+	defer bc.mu.RUnlock()
+
+	confirmed, err := bc.storage.GetAccountNonce(address)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read account nonce: %v", err)
+	}
+	pending := bc.pool.MaxNonce(address)
+
+	return &AccountNonce{
+		Address:        address,
+		ConfirmedNonce: confirmed,
+		PendingNonce:   pending,
+		NextNonce:      max(confirmed, pending) + 1,
+	}, nil
+}
@@ -1,23 +1,163 @@
 package blockchain
 
 import (
-    "time"
+	"time"
+
+	"twichain/internal/consensus"
+	"twichain/internal/crypto"
+	"twichain/internal/storage"
 )
 
 type Block struct {
-    Index        int         `json:"index"`
-    Timestamp    time.Time   `json:"timestamp"`
-    Transactions []Transaction `json:"transactions"`
-    Proof        int64       `json:"proof"`
-    PrevHash     string      `json:"previous_hash"`
+	Index        int           `json:"index"`
+	Timestamp    time.Time     `json:"timestamp"`
+	Transactions []Transaction `json:"transactions"`
+	Proof        int64         `json:"proof"`
+	PrevHash     string        `json:"previous_hash"`
+	Difficulty   int           `json:"difficulty,omitempty"`  // 出块时生效的难度，旧区块为 0
+	MerkleRoot   string        `json:"merkle_root,omitempty"` // 交易哈希的 Merkle 根，旧区块为空
+	Version      int           `json:"version,omitempty"`     // 区块编码版本，旧区块为 0
+	Validator    string        `json:"validator,omitempty"`   // 出块验证者公钥（PoA）
+	Seal         string        `json:"seal,omitempty"`        // 出块验证者签名（PoA）
+}
+
+// Header 返回参与区块哈希计算的区块头，交易通过 MerkleRoot 间接覆盖
+func (b *Block) Header() *consensus.Header {
+	return &consensus.Header{
+		Version:    b.Version,
+		Index:      b.Index,
+		Timestamp:  b.Timestamp,
+		PrevHash:   b.PrevHash,
+		MerkleRoot: b.MerkleRoot,
+		Proof:      b.Proof,
+		Difficulty: b.Difficulty,
+		Validator:  b.Validator,
+		Seal:       b.Seal,
+	}
+}
+
+// setHeader 写回共识引擎填充的区块头字段
+func (b *Block) setHeader(header *consensus.Header) {
+	b.Proof = header.Proof
+	b.Difficulty = header.Difficulty
+	b.Validator = header.Validator
+	b.Seal = header.Seal
+}
+
+// Hash 计算区块哈希，只覆盖区块头的规范编码
+// 版本 0 的旧区块仍按整个区块的 JSON 计算，以保持原有哈希链接
+func (b *Block) Hash() string {
+	if b.Version < consensus.VersionCanonical {
+		return crypto.HashBlock(b)
+	}
+	return crypto.HashBlock(*b.Header())
+}
+
+// headerReader 将区块切片适配为共识引擎使用的 ChainReader
+type headerReader []*Block
+
+func (r headerReader) Len() int {
+	return len(r)
+}
+
+func (r headerReader) HeaderAt(i int) *consensus.Header {
+	return r[i].Header()
+}
+
+// computeMerkleRoot 计算交易列表的 Merkle 根
+func computeMerkleRoot(transactions []Transaction) string {
+	root, err := crypto.MerkleRoot(transactionHashes(transactions))
+	if err != nil {
+		return ""
+	}
+	return root
+}
+
+// transactionHashes 返回交易哈希列表，作为 Merkle 树的叶子
+func transactionHashes(transactions []Transaction) []string {
+	hashes := make([]string, len(transactions))
+	for i := range transactions {
+		hashes[i] = transactions[i].Hash()
+	}
+	return hashes
 }
 
 func NewBlock(index int, transactions []Transaction, proof int64, prevHash string) *Block {
-    return &Block{
-        Index:        index,
-        Timestamp:    time.Now(),
-        Transactions: transactions,
-        Proof:        proof,
-        PrevHash:     prevHash,
-    }
-}
\ No newline at end of file
+	return &Block{
+		Index:        index,
+		Timestamp:    time.Now(),
+		Transactions: transactions,
+		Proof:        proof,
+		PrevHash:     prevHash,
+		MerkleRoot:   computeMerkleRoot(transactions),
+		Version:      consensus.VersionCurrent,
+	}
+}
+
+// toBlockData 将区块转换为存储格式
+func toBlockData(block *Block) *storage.BlockData {
+	blockData := &storage.BlockData{
+		Index:        block.Index,
+		Timestamp:    block.Timestamp,
+		Proof:        block.Proof,
+		PrevHash:     block.PrevHash,
+		Difficulty:   block.Difficulty,
+		MerkleRoot:   block.MerkleRoot,
+		Version:      block.Version,
+		Validator:    block.Validator,
+		Seal:         block.Seal,
+		Transactions: make([]storage.TransactionData, len(block.Transactions)),
+	}
+
+	for i, tx := range block.Transactions {
+		blockData.Transactions[i] = storage.TransactionData{
+			ID:           tx.ID,
+			Sender:       tx.Sender,
+			Receiver:     tx.Receiver,
+			Signature:    tx.Signature,
+			IsLike:       tx.IsLike,
+			Timestamp:    tx.Timestamp,
+			Message:      tx.Message,
+			TargetPostID: tx.TargetPostID,
+			Version:      tx.Version,
+			Nonce:        tx.Nonce,
+			Kind:         string(tx.Kind),
+		}
+	}
+
+	return blockData
+}
+
+// fromBlockData 将存储格式还原为区块
+func fromBlockData(blockData *storage.BlockData) *Block {
+	block := &Block{
+		Index:        blockData.Index,
+		Timestamp:    blockData.Timestamp,
+		Proof:        blockData.Proof,
+		PrevHash:     blockData.PrevHash,
+		Difficulty:   blockData.Difficulty,
+		MerkleRoot:   blockData.MerkleRoot,
+		Version:      blockData.Version,
+		Validator:    blockData.Validator,
+		Seal:         blockData.Seal,
+		Transactions: make([]Transaction, len(blockData.Transactions)),
+	}
+
+	for i, tx := range blockData.Transactions {
+		block.Transactions[i] = Transaction{
+			ID:           tx.ID,
+			Sender:       tx.Sender,
+			Receiver:     tx.Receiver,
+			Signature:    tx.Signature,
+			IsLike:       tx.IsLike,
+			Timestamp:    tx.Timestamp,
+			Message:      tx.Message,
+			TargetPostID: tx.TargetPostID,
+			Version:      tx.Version,
+			Nonce:        tx.Nonce,
+			Kind:         Kind(tx.Kind),
+		}
+	}
+
+	return block
+}
@@ -2,29 +2,51 @@ package blockchain
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log"
 	"net/http"
 	"net/url"
-	"strconv"
 	"strings"
 	"sync"
 	"time"
 
+	"twichain/internal/config"
+	"twichain/internal/consensus"
 	"twichain/internal/crypto"
+	"twichain/internal/mempool"
 	"twichain/internal/storage"
 )
 
+// 未配置链 ID 和主空间地址时使用的默认值
+const (
+	defaultChainID   = "twichain"
+	defaultMainSpace = "69c5f684026e6bd3e2a8f175a892ca6858cb9936b3c525ce11b981f848a69fc2"
+)
+
 type Blockchain struct {
-    Chain                    []*Block             `json:"chain"`
-    CurrentTransactions      []Transaction        `json:"current_transactions"`
-    Nodes                    map[string]bool      `json:"nodes"`
-    mu                       sync.RWMutex         `json:"-"`
-    storage                  storage.BlockStorage `json:"-"`
-    Difficulty              int                  `json:"difficulty"`
-    port                    string               `json:"-"` // 添加端口字段
+	Chain      []*Block                   `json:"chain"`
+	pool       *mempool.Pool[Transaction] `json:"-"` // 待打包交易池
+	Nodes      map[string]bool            `json:"nodes"`
+	mu         sync.RWMutex               `json:"-"`
+	storage    storage.BlockStorage       `json:"-"`
+	engine     consensus.Engine           `json:"-"` // 共识引擎
+	policy     MiningPolicy               `json:"-"` // 出块策略
+	mineSignal chan struct{}              `json:"-"` // 立即出块通知
+	tipCtx     context.Context            `json:"-"` // 链顶变化时取消，用于中止过期的挖矿
+	tipCancel  context.CancelFunc         `json:"-"`
+	port       string                     `json:"-"` // 添加端口字段
+	resolving  sync.Mutex                 `json:"-"` // 防止并发处理分叉
+	seen       *seenCache                 `json:"-"` // 最近处理过的交易，防止重复转发
+	chainID    string                     `json:"-"` // 交易签名的链 ID，防止跨网络重放
+	state      *chainState                `json:"-"` // 重放到链顶的链上状态
+	threads    ThreadPolicy               `json:"-"` // 讨论串查询限制
+	mainSpace  string                     `json:"-"` // 主空间地址
+
+	announcements *announceQueue `json:"-"` // 待广播交易，按加入交易池的顺序广播
 }
 
 // GetChain 返回区块链的副本
@@ -44,163 +66,220 @@ func (bc *Blockchain) GetChainLength() int {
 	return length
 }
 
-func NewBlockchain(store storage.BlockStorage, nodeAddress string, port string) *Blockchain {
-    log.Printf("Initializing new blockchain on port %s", port)
+func NewBlockchain(store storage.BlockStorage, cfg *config.Config) *Blockchain {
+	nodeAddress := cfg.Blockchain.NodeAddress
+	port := cfg.Server.Port
+	log.Printf("Initializing new blockchain on port %s", port)
+
+	engine, err := consensus.NewEngine(cfg)
+	if err != nil {
+		log.Printf("Failed to create consensus engine: %v", err)
+		return nil
+	}
+	log.Printf("Using consensus engine: %s", engine.Name())
+
+	bc := &Blockchain{
+		Chain: make([]*Block, 0),
+		pool: mempool.New[Transaction](mempool.Config{
+			MaxSize:      cfg.Mempool.MaxSize,
+			MaxPerSender: cfg.Mempool.MaxPerSender,
+			TTL:          time.Duration(cfg.Mempool.TTL) * time.Second,
+		}),
+		Nodes:      make(map[string]bool),
+		storage:    store,
+		engine:     engine,
+		policy:     newMiningPolicy(cfg),
+		mineSignal: make(chan struct{}, 1),
+		port:       port,
+		seen:       newSeenCache(seenTransactionTTL, seenTransactionLimit),
+		chainID:    cfg.Blockchain.ChainID,
+		state:      newChainState(),
+		threads:    newThreadPolicy(cfg),
+		mainSpace:  cfg.Blockchain.MainSpace,
+
+		announcements: newAnnounceQueue(),
+	}
+	if bc.chainID == "" {
+		bc.chainID = defaultChainID
+	}
+	if bc.mainSpace == "" {
+		bc.mainSpace = defaultMainSpace
+	}
+	if !crypto.ValidateAddress(bc.mainSpace) {
+		log.Printf("Invalid main space address %q: must be 256-bit hex string", bc.mainSpace)
+		return nil
+	}
+	bc.tipCtx, bc.tipCancel = context.WithCancel(context.Background())
 
-    bc := &Blockchain{
-        Chain:               make([]*Block, 0),
-        CurrentTransactions: make([]Transaction, 0),
-        Nodes:              make(map[string]bool),
-        storage:            store,
-        Difficulty:         2,
-        port:              port,
-    }
+	// 优先从本地数据库恢复区块链
+	loaded, err := bc.loadFromStorage()
+	if err != nil {
+		log.Printf("Failed to load blockchain from storage: %v", err)
+		return nil
+	}
 
-	// 如果配置了节点地址,从该节点同步数据
-	if nodeAddress != "" {
+	if loaded {
+		log.Printf("Restored %d blocks and %d nodes from storage", len(bc.Chain), len(bc.Nodes))
+		// 离线期间网络可能已经前进，启动后与已知节点对齐
+		go bc.resolveInBackground()
+	} else if nodeAddress != "" {
+		// 如果配置了节点地址,从该节点同步数据
 		if err := bc.syncFromNode(nodeAddress); err != nil {
 			log.Printf("Failed to sync from node %s: %v", nodeAddress, err)
 			return nil
 		}
 	} else {
-		// 创建创世块
+		// 数据库为空时才创建创世块
 		genesisTransaction := Transaction{
-			ID:        generateTransactionID(),
-			Sender:    "SYSTEM",
-			Receiver:  "69c5f684026e6bd3e2a8f175a892ca6858cb9936b3c525ce11b981f848a69fc2",
+			Sender:    systemSender,
+			Receiver:  bc.mainSpace,
 			Signature: "GENESIS", // 创世块不需要签名验证
 			IsLike:    false,
 			Message:   "Genesis Block - Social Blockchain Initialized",
 			Timestamp: time.Now(),
+			Version:   TransactionVersionCurrent,
+			Kind:      KindPost,
 		}
+		genesisTransaction.ID = genesisTransaction.ComputeID()
 
-		bc.CurrentTransactions = append(bc.CurrentTransactions, genesisTransaction)
-		genesisBlock := bc.NewBlock(100, "1")
+		bc.pool.Add(genesisTransaction)
+		genesisBlock := bc.NewBlock(genesisProof, genesisPrevHash)
 		log.Printf("Genesis block created with social transaction: %+v", genesisBlock)
 	}
 
-	// 启动定时挖矿
+	// 启动交易广播和定时挖矿
+	go bc.announceLoop()
 	bc.StartMining()
 	return bc
 }
 
+// loadFromStorage 从数据库恢复区块链和节点列表，数据库为空时返回 false
+func (bc *Blockchain) loadFromStorage() (bool, error) {
+	blocksData, err := bc.storage.GetAllBlocks()
+	if err != nil {
+		return false, fmt.Errorf("failed to read blocks: %v", err)
+	}
+	if len(blocksData) == 0 {
+		return false, nil
+	}
+
+	chain := make([]*Block, len(blocksData))
+	for i, blockData := range blocksData {
+		chain[i] = fromBlockData(blockData)
+	}
+
+	// 重新验证整条链，防止数据库被篡改
+	state, err := bc.replayChain(chain)
+	if err != nil {
+		return false, err
+	}
+
+	nodes, err := bc.storage.GetAllNodes()
+	if err != nil {
+		return false, fmt.Errorf("failed to read nodes: %v", err)
+	}
+
+	bc.Chain = chain
+	bc.state = state
+	for _, node := range nodes {
+		bc.Nodes[node] = true
+	}
+
+	return true, nil
+}
+
 func (bc *Blockchain) NewBlock(proof int64, previousHash string) *Block {
 	bc.mu.Lock()
 	defer bc.mu.Unlock()
 
+	transactions := bc.pool.Select(0)
 	block := &Block{
 		Index:        len(bc.Chain) + 1,
-		Timestamp:    time.Now(),
-		Transactions: bc.CurrentTransactions, // 改为大写
+		Timestamp:    nextBlockTime(bc.Chain),
+		Transactions: transactions,
 		Proof:        proof,
 		PrevHash:     previousHash,
+		MerkleRoot:   computeMerkleRoot(transactions),
+		Version:      consensus.VersionCurrent,
 	}
 
-	// 转换为存储格式并保存
-	blockData := &storage.BlockData{
-		Index:        block.Index,
-		Timestamp:    block.Timestamp,
-		Proof:        block.Proof,
-		PrevHash:     block.PrevHash,
-		Transactions: make([]storage.TransactionData, len(block.Transactions)),
-	}
-
-	for i, tx := range block.Transactions {
-		blockData.Transactions[i] = storage.TransactionData{
-			ID:        tx.ID,
-			Sender:    tx.Sender,
-			Receiver:  tx.Receiver,
-			Signature: tx.Signature,
-			IsLike:    tx.IsLike,
-			Timestamp: tx.Timestamp,
-			Message:   tx.Message,
-		}
+	header := block.Header()
+	if err := bc.engine.Prepare(headerReader(bc.Chain), header); err != nil {
+		log.Printf("Error preparing block: %v", err)
 	}
+	block.Difficulty = header.Difficulty
 
-	if err := bc.storage.SaveBlock(blockData); err != nil {
+	next, err := bc.state.executeBlock(block)
+	if err != nil {
+		log.Printf("Error executing block: %v", err)
+		return nil
+	}
+
+	// 转换为存储格式并保存
+	if err := bc.storage.SaveBlock(toBlockData(block)); err != nil {
 		log.Printf("Error saving block: %v", err)
 	}
+	next.commit()
 
-	// 重置当前交易
-	bc.CurrentTransactions = make([]Transaction, 0) // 改为大写
+	// 移除已打包的交易
+	bc.removeIncludedTransactions([]*Block{block})
 	bc.Chain = append(bc.Chain, block)
+	bc.advanceTip()
 	return block
 }
 
-// 用于生成交易ID
-func generateTransactionID() string {
-	return crypto.Hash([]byte(time.Now().String()))
-}
+// RegisterNode 注册一个新的节点到网络中
+func (bc *Blockchain) RegisterNode(address string) error {
+	// 1. 首先进行地址验证（不需要锁）
+	parsedURL, err := url.Parse(address)
+	if err != nil {
+		return fmt.Errorf("invalid address format: %v", err)
+	}
 
-func (bc *Blockchain) ProofOfWork(lastBlock *Block) int64 {
-	lastProof := lastBlock.Proof
-	lastHash := crypto.HashBlock(lastBlock)
+	if parsedURL.Host == "" {
+		return fmt.Errorf("invalid address: no host found")
+	}
 
-	var proof int64 = 0
-	for !bc.ValidProof(lastProof, proof, lastHash) {
-		proof++
+	// 2. 检查节点是否存在（需要读锁）
+	bc.mu.RLock()
+	exists := bc.Nodes[parsedURL.Host]
+	bc.mu.RUnlock()
+
+	if exists {
+		return fmt.Errorf("node already exists: %s", parsedURL.Host)
 	}
 
-	return proof
-}
+	// 3. 检查数据库（不需要锁）
+	nodes, err := bc.storage.GetAllNodes()
+	if err != nil {
+		return fmt.Errorf("failed to check existing nodes: %v", err)
+	}
+	for _, node := range nodes {
+		if node == parsedURL.Host {
+			return fmt.Errorf("node already exists in database: %s", parsedURL.Host)
+		}
+	}
 
-// ValidProof 验证工作量证明
-func (bc *Blockchain) ValidProof(lastProof, proof int64, lastHash string) bool {
-	guess := []byte(strconv.FormatInt(lastProof, 10) + strconv.FormatInt(proof, 10) + lastHash)
-	guessHash := crypto.Hash(guess)
-	zeros := strings.Repeat("0", bc.Difficulty)
-	return guessHash[:bc.Difficulty] == zeros
-}
+	// 4. 保存节点（需要写锁）
+	bc.mu.Lock()
+	if err := bc.storage.SaveNode(parsedURL.Host); err != nil {
+		bc.mu.Unlock()
+		return fmt.Errorf("failed to save node: %v", err)
+	}
+	bc.Nodes[parsedURL.Host] = true
+	bc.mu.Unlock()
 
-// RegisterNode 注册一个新的节点到网络中
-func (bc *Blockchain) RegisterNode(address string) error {
-    // 1. 首先进行地址验证（不需要锁）
-    parsedURL, err := url.Parse(address)
-    if err != nil {
-        return fmt.Errorf("invalid address format: %v", err)
-    }
-
-    if parsedURL.Host == "" {
-        return fmt.Errorf("invalid address: no host found")
-    }
-
-    // 2. 检查节点是否存在（需要读锁）
-    bc.mu.RLock()
-    exists := bc.Nodes[parsedURL.Host]
-    bc.mu.RUnlock()
-    
-    if exists {
-        return fmt.Errorf("node already exists: %s", parsedURL.Host)
-    }
-
-    // 3. 检查数据库（不需要锁）
-    nodes, err := bc.storage.GetAllNodes()
-    if err != nil {
-        return fmt.Errorf("failed to check existing nodes: %v", err)
-    }
-    for _, node := range nodes {
-        if node == parsedURL.Host {
-            return fmt.Errorf("node already exists in database: %s", parsedURL.Host)
-        }
-    }
-
-    // 4. 保存节点（需要写锁）
-    bc.mu.Lock()
-    if err := bc.storage.SaveNode(parsedURL.Host); err != nil {
-        bc.mu.Unlock()
-        return fmt.Errorf("failed to save node: %v", err)
-    }
-    bc.Nodes[parsedURL.Host] = true
-    bc.mu.Unlock()
-
-    // 5. 广播新节点（不需要锁）
-    go bc.BroadcastNewNode(address)  // 异步执行广播
-
-    return nil
+	// 5. 广播新节点（不需要锁）
+	go bc.BroadcastNewNode(address) // 异步执行广播
+
+	return nil
 }
 
-// 添加节点删除方法
+// removeNode 删除节点，广播协程在不持有锁时调用，与 RegisterNode 一样在写锁内修改节点列表
 func (bc *Blockchain) removeNode(address string) {
+	bc.mu.Lock()
+	defer bc.mu.Unlock()
+
 	if err := bc.storage.DeleteNode(address); err != nil {
 		log.Printf("Failed to delete node from storage: %v", err)
 	}
@@ -237,108 +316,155 @@ func (bc *Blockchain) BroadcastNewNode(newNode string) {
 	}
 }
 
-func (bc *Blockchain) NewTransaction(sender, receiver, signature string, isLike bool, message string, targetPostID string) int {
-	transaction := Transaction{
-		ID:           generateTransactionID(),
-		Sender:       sender,
-		Receiver:     receiver,
-		Signature:    signature,
-		IsLike:       isLike,
-		Message:      message,
-		Timestamp:    time.Now(),
-		TargetPostID: targetPostID,
+// NewTransaction 接收客户端签名的交易，计算内容寻址的 ID，验证后加入交易池并异步广播给其他节点
+// 返回交易 ID 和交易预计被打包进的区块索引
+func (bc *Blockchain) NewTransaction(transaction Transaction) (string, int, error) {
+	// 未指定类型的客户端按 IsLike 和 TargetPostID 推导，指定类型时 IsLike 与类型保持一致
+	if transaction.Kind == "" {
+		transaction.Kind = transaction.kind()
+	} else {
+		transaction.IsLike = transaction.Kind == KindLike
+	}
+	transaction.ID = transaction.ComputeID()
+	nextBlockIndex, err := bc.addPendingTransaction(transaction)
+	if err != nil {
+		return "", 0, err
+	}
+
+	// 记录为已见，避免其他节点转发回来时再次处理
+	bc.seen.markSeen(transaction.ID)
+
+	return transaction.ID, nextBlockIndex, nil
+}
+
+// addPendingTransaction 完整验证交易后加入交易池并排队广播，返回交易预计被打包进的区块索引
+func (bc *Blockchain) addPendingTransaction(tx Transaction) (int, error) {
+	if err := bc.validateTransaction(&tx); err != nil {
+		return 0, err
 	}
 
 	bc.mu.Lock()
-	bc.CurrentTransactions = append(bc.CurrentTransactions, transaction)
+	if err := bc.checkPending(&tx); err != nil {
+		bc.mu.Unlock()
+		return 0, err
+	}
+	if err := bc.pool.Add(tx); err != nil {
+		bc.mu.Unlock()
+		return 0, err
+	}
+	// 在加入交易池的同一把锁内入队，广播顺序与交易池中的序号顺序一致
+	bc.announcements.push(tx)
 	nextBlockIndex := len(bc.Chain) + 1
+	mineNow := bc.thresholdReached()
 	bc.mu.Unlock()
 
-	return nextBlockIndex
+	if mineNow {
+		bc.signalMining()
+	}
+	return nextBlockIndex, nil
+}
+
+// ChainID 返回交易签名使用的链 ID
+func (bc *Blockchain) ChainID() string {
+	return bc.chainID
 }
 
 func (bc *Blockchain) Mine() {
-    // 1. 检查并复制交易（使用读锁）
-    bc.mu.RLock()
-    if len(bc.CurrentTransactions) == 0 {
-        bc.mu.RUnlock()
-        return
-    }
-    transactions := make([]Transaction, len(bc.CurrentTransactions))
-    copy(transactions, bc.CurrentTransactions)
-    lastBlock := bc.Chain[len(bc.Chain)-1]
-    bc.mu.RUnlock()
-
-    // 2. 进行工作量证明计算（不需要锁）
-    proof := bc.ProofOfWork(lastBlock)
-    lastHash := crypto.HashBlock(lastBlock)
-
-    // 3. 创建新区块
-    block := &Block{
-        Index:        lastBlock.Index + 1,
-        Timestamp:    time.Now(),
-        Transactions: transactions,
-        Proof:        proof,
-        PrevHash:     lastHash,
-    }
-
-    // 4. 保存区块（使用写锁）
-    bc.mu.Lock()
-    // 再次检查条件
-    if block.Index != bc.Chain[len(bc.Chain)-1].Index+1 {
-        bc.mu.Unlock()
-        return
-    }
-
-    // 保存区块数据
-	blockData := &storage.BlockData{
-		Index:        block.Index,
-		Timestamp:    block.Timestamp,
-		Proof:        block.Proof,
-		PrevHash:     block.PrevHash,
-		Transactions: make([]storage.TransactionData, len(block.Transactions)),
-	}
-
-	// 转换交易数据
-	for i, tx := range block.Transactions {
-		blockData.Transactions[i] = storage.TransactionData{
-			ID:           tx.ID,
-			Sender:       tx.Sender,
-			Receiver:     tx.Receiver,
-			Signature:    tx.Signature,
-			IsLike:       tx.IsLike,
-			Timestamp:    tx.Timestamp,
-			Message:      tx.Message,
-			TargetPostID: tx.TargetPostID,
+	// 1. 检查并按策略选择交易（使用读锁）
+	bc.mu.RLock()
+	transactions := bc.selectTransactions()
+	// 交易池为空或其中的交易都无法执行时，除非允许出空块，否则不出块
+	if len(transactions) == 0 && !bc.policy.EmptyBlocks {
+		bc.mu.RUnlock()
+		return
+	}
+	chain := make(headerReader, len(bc.Chain))
+	copy(chain, bc.Chain)
+	tipCtx := bc.tipCtx
+	bc.mu.RUnlock()
+
+	// 2. 创建新区块并由共识引擎封装（不需要锁）
+	lastBlock := chain[len(chain)-1]
+	block := &Block{
+		Index:        lastBlock.Index + 1,
+		Timestamp:    nextBlockTime(chain),
+		Transactions: transactions,
+		PrevHash:     lastBlock.Hash(),
+		MerkleRoot:   computeMerkleRoot(transactions),
+		Version:      consensus.VersionCurrent,
+	}
+
+	header := block.Header()
+	if err := bc.engine.Prepare(chain, header); err != nil {
+		log.Printf("Error preparing block: %v", err)
+		return
+	}
+	if err := bc.engine.Seal(tipCtx, chain, header); err != nil {
+		switch {
+		case errors.Is(err, consensus.ErrNotInTurn):
+			// 未轮到本节点出块不是错误
+		case errors.Is(err, context.Canceled):
+			log.Printf("Mining of block %d aborted: chain tip changed", block.Index)
+		default:
+			log.Printf("Error sealing block: %v", err)
 		}
+		return
+	}
+	block.setHeader(header)
+
+	// 4. 保存区块（使用写锁）
+	bc.mu.Lock()
+	// 再次检查条件，挖矿期间链可能已经前进或被替换
+	tip := bc.Chain[len(bc.Chain)-1]
+	if block.Index != tip.Index+1 || block.PrevHash != tip.Hash() {
+		bc.mu.Unlock()
+		return
+	}
+	next, err := bc.state.executeBlock(block)
+	if err != nil {
+		log.Printf("Error executing block: %v", err)
+		bc.mu.Unlock()
+		return
 	}
 
-	if err := bc.storage.SaveBlock(blockData); err != nil {
+	// 保存区块数据
+	if err := bc.storage.SaveBlock(toBlockData(block)); err != nil {
 		log.Printf("Error saving block: %v", err)
 		bc.mu.Unlock()
 		return
 	}
 
-    // 更新内存状态
-    bc.Chain = append(bc.Chain, block)
-    bc.CurrentTransactions = bc.CurrentTransactions[len(transactions):]
-    bc.mu.Unlock()
+	// 更新内存状态
+	next.commit()
+	bc.Chain = append(bc.Chain, block)
+	bc.advanceTip()
+	bc.removeIncludedTransactions([]*Block{block})
+	bc.revalidatePool()
+	bc.mu.Unlock()
 
-    // 5. 广播新区块（不需要锁）
-    go bc.AnnounceNewBlock(block)  // 异步执行广播
+	// 5. 广播新区块（不需要锁）
+	go bc.AnnounceNewBlock(block) // 异步执行广播
 }
 
+// StartMining 按出块策略启动挖矿：定时出块，交易池达到阈值时立即出块
 func (bc *Blockchain) StartMining() {
-	ticker := time.NewTicker(1 * time.Minute)
+	ticker := time.NewTicker(bc.policy.BlockInterval)
 	go func() {
-		for range ticker.C {
-			bc.mu.RLock()
-			if len(bc.CurrentTransactions) == 0 {
-				bc.mu.RUnlock()
-				continue
+		for {
+			select {
+			case <-ticker.C:
+			case <-bc.mineSignal:
 			}
-			bc.mu.RUnlock()
+			before := bc.GetChainLength()
 			bc.Mine()
+
+			// 单个区块装不下时，剩余交易仍达到阈值则继续出块
+			bc.mu.RLock()
+			mineAgain := len(bc.Chain) > before && bc.thresholdReached()
+			bc.mu.RUnlock()
+			if mineAgain {
+				bc.signalMining()
+			}
 		}
 	}()
 }
@@ -351,17 +477,9 @@ func (bc *Blockchain) AnnounceNewBlock(block *Block) {
 		return
 	}
 
-	blockData := map[string]interface{}{
-		"index":         block.Index,
-		"transactions":  block.Transactions,
-		"timestamp":     block.Timestamp,
-		"proof":         block.Proof,
-		"previous_hash": block.PrevHash,
-	}
-
 	for _, node := range nodes {
 		url := fmt.Sprintf("http://%s/block/receive", node)
-		jsonData, _ := json.Marshal(blockData)
+		jsonData, _ := json.Marshal(block)
 
 		resp, err := http.Post(url, "application/json", bytes.NewBuffer(jsonData))
 		if err != nil {
@@ -383,141 +501,100 @@ func (bc *Blockchain) AddBlock(block *Block) error {
 
 	// 验证区块
 	lastBlock := bc.Chain[len(bc.Chain)-1]
-	if block.Index != lastBlock.Index+1 {
-		return fmt.Errorf("invalid block index")
-	}
-
-	if block.PrevHash != crypto.HashBlock(lastBlock) {
-		return fmt.Errorf("invalid previous hash")
-	}
-
-	// 验证工作量证明
-	if !bc.ValidProof(lastBlock.Proof, block.Proof, block.PrevHash) {
-		return fmt.Errorf("invalid proof of work")
-	}
-
-	// 验证所有交易的签名
-	for _, tx := range block.Transactions {
-		var messageBytes []byte
-		if tx.IsLike {
-			messageBytes = []byte(tx.TargetPostID)
-		} else {
-			messageBytes = []byte(tx.Message)
-		}
-
-		valid, err := crypto.Verify(tx.Sender, messageBytes, tx.Signature)
-		if err != nil || !valid {
-			return fmt.Errorf("invalid transaction signature: %v", err)
+	if err := bc.validateBlock(block, bc.Chain); err != nil {
+		// 对方的链比本地链长却无法衔接，说明本地落后或出现了分叉，异步触发冲突处理
+		// 签名、工作量证明等其他验证失败说明区块本身无效，拉取整条链也无济于事
+		if !linksTo(block, lastBlock) && block.Index > lastBlock.Index {
+			go bc.resolveInBackground()
 		}
+		return err
 	}
-
-	// 转换为存储格式并保存
-	blockData := &storage.BlockData{
-		Index:        block.Index,
-		Timestamp:    block.Timestamp,
-		Proof:        block.Proof,
-		PrevHash:     block.PrevHash,
-		Transactions: make([]storage.TransactionData, len(block.Transactions)),
-	}
-
-	// 转换交易数据
-	for i, tx := range block.Transactions {
-		blockData.Transactions[i] = storage.TransactionData{
-			ID:           tx.ID,
-			Sender:       tx.Sender,
-			Receiver:     tx.Receiver,
-			Signature:    tx.Signature,
-			IsLike:       tx.IsLike,
-			Timestamp:    tx.Timestamp,
-			Message:      tx.Message,
-			TargetPostID: tx.TargetPostID,
-		}
+	next, err := bc.state.executeBlock(block)
+	if err != nil {
+		return err
 	}
 
 	// 保存到存储
-	if err := bc.storage.SaveBlock(blockData); err != nil {
+	if err := bc.storage.SaveBlock(toBlockData(block)); err != nil {
 		return fmt.Errorf("failed to save block: %v", err)
 	}
 
 	// 添加到链中
+	next.commit()
 	bc.Chain = append(bc.Chain, block)
+	bc.advanceTip()
 
-	// 清理当前交易池中已经被打包的交易
-	// bc.CurrentTransactions = make([]Transaction, 0)
+	// 清理当前交易池中已经被打包和在新链顶上无法执行的交易
+	bc.removeIncludedTransactions([]*Block{block})
+	bc.revalidatePool()
 
 	return nil
 }
 
+// linksTo 判断区块的索引和父哈希能否直接接在 parent 之后
+func linksTo(block, parent *Block) bool {
+	return block.Index == parent.Index+1 && block.PrevHash == parent.Hash()
+}
+
 // 同步区块链数据
 func (bc *Blockchain) syncFromNode(nodeAddress string) error {
 	// 创建请求数据
-    data := map[string]string{
-        "node": fmt.Sprintf("http://localhost:%s", bc.port),  // 需要在 Blockchain 结构体中添加 port 字段
-    }
-    jsonData, err := json.Marshal(data)
-    if err != nil {
-        return fmt.Errorf("failed to marshal request data: %v", err)
-    }
-
-    // 发送 POST 请求
-    resp, err := http.Post(
-        fmt.Sprintf("http://%s/nodes/register", nodeAddress),
-        "application/json",
-        bytes.NewBuffer(jsonData),
-    )
-    if err != nil {
-        log.Printf("Failed to sync from node %s: %v", nodeAddress, err)
-        return err
-    }
-    defer resp.Body.Close()
-
-    // 读取响应内容进行调试
-    body, err := io.ReadAll(resp.Body)
-    if err != nil {
-        return fmt.Errorf("failed to read response body: %v", err)
-    }
-    log.Printf("Response from node: %s", string(body))
-
-    // 解码响应
-    var result struct {
-        Chain []*Block        `json:"chain"`
-        Nodes map[string]bool `json:"nodes"`
-    }
-
-    if err := json.NewDecoder(bytes.NewReader(body)).Decode(&result); err != nil {
-        log.Printf("Failed to decode response from node %s: %v", nodeAddress, err)
-        log.Printf("Response body: %s", string(body))
-        return err
-    }
+	data := map[string]string{
+		"node": fmt.Sprintf("http://localhost:%s", bc.port), // 需要在 Blockchain 结构体中添加 port 字段
+	}
+	jsonData, err := json.Marshal(data)
+	if err != nil {
+		return fmt.Errorf("failed to marshal request data: %v", err)
+	}
+
+	// 发送 POST 请求
+	resp, err := http.Post(
+		fmt.Sprintf("http://%s/nodes/register", nodeAddress),
+		"application/json",
+		bytes.NewBuffer(jsonData),
+	)
+	if err != nil {
+		log.Printf("Failed to sync from node %s: %v", nodeAddress, err)
+		return err
+	}
+	defer resp.Body.Close()
+
+	// 读取响应内容进行调试
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read response body: %v", err)
+	}
+	log.Printf("Response from node: %s", string(body))
+
+	// 解码响应
+	var result struct {
+		Chain []*Block        `json:"chain"`
+		Nodes map[string]bool `json:"nodes"`
+	}
+
+	if err := json.NewDecoder(bytes.NewReader(body)).Decode(&result); err != nil {
+		log.Printf("Failed to decode response from node %s: %v", nodeAddress, err)
+		log.Printf("Response body: %s", string(body))
+		return err
+	}
+
+	// 在信任同步数据之前验证整条链
+	state, err := bc.replayChain(result.Chain)
+	if err != nil {
+		return fmt.Errorf("invalid chain from node %s: %v", nodeAddress, err)
+	}
 
 	// 保存链和节点信息到内存
 	bc.Chain = result.Chain
-	bc.Nodes = result.Nodes
+	bc.state = state
+	for nodeAddr := range result.Nodes {
+		bc.Nodes[nodeAddr] = true
+	}
 	bc.Nodes[nodeAddress] = true
 
 	// 保存区块到存储
 	for _, block := range result.Chain {
-		blockData := &storage.BlockData{
-			Index:        block.Index,
-			Timestamp:    block.Timestamp,
-			Proof:        block.Proof,
-			PrevHash:     block.PrevHash,
-			Transactions: make([]storage.TransactionData, len(block.Transactions)),
-		}
-		// 转换交易数据
-		for i, tx := range block.Transactions {
-			blockData.Transactions[i] = storage.TransactionData{
-				ID:           tx.ID,
-				Sender:       tx.Sender,
-				Receiver:     tx.Receiver,
-				Signature:    tx.Signature,
-				IsLike:       tx.IsLike,
-				Timestamp:    tx.Timestamp,
-				Message:      tx.Message,
-				TargetPostID: tx.TargetPostID,
-			}
-		}
-		if err := bc.storage.SaveBlock(blockData); err != nil {
+		if err := bc.storage.SaveBlock(toBlockData(block)); err != nil {
 			return fmt.Errorf("failed to save block: %v", err)
 		}
 	}
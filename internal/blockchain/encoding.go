@@ -0,0 +1,80 @@
+package blockchain
+
+import (
+	"twichain/internal/crypto"
+)
+
+// 交易规范编码版本，签名信封格式的交易额外编码交易版本和序号
+const (
+	transactionEncodingVersion         = 1
+	transactionEnvelopeEncodingVersion = 2
+	transactionIDEncodingVersion       = 1
+)
+
+// 交易签名的域分隔字符串，防止交易签名被当作其他消息的签名使用
+const transactionSignDomain = "twichain/transaction"
+
+// CanonicalBytes 返回交易的规范编码，字段顺序固定：
+// id, sender, receiver, signature, is_like, timestamp(unix nano), message, target_post_id
+// 签名信封格式的交易在末尾追加 version, nonce
+func (tx *Transaction) CanonicalBytes() []byte {
+	if tx.Version < TransactionVersionEnvelope {
+		return tx.writeFields(crypto.NewEncoder(transactionEncodingVersion).WriteString(tx.ID)).Bytes()
+	}
+	return tx.writeEnvelope(
+		tx.writeFields(crypto.NewEncoder(transactionEnvelopeEncodingVersion).WriteString(tx.ID)),
+	).Bytes()
+}
+
+// bodyBytes 返回交易 ID 之外的已签名交易体的规范编码，字段顺序固定：
+// sender, receiver, signature, is_like, timestamp(unix nano), message, target_post_id
+// 签名信封格式的交易在末尾追加 version, nonce
+func (tx *Transaction) bodyBytes() []byte {
+	e := tx.writeFields(crypto.NewEncoder(transactionIDEncodingVersion))
+	if tx.Version >= TransactionVersionEnvelope {
+		e = tx.writeEnvelope(e)
+	}
+	return e.Bytes()
+}
+
+// SignBytes 返回交易签名覆盖的字节，首字节为交易版本号，字段顺序固定：
+// domain, chain_id, sender, receiver, kind, message, target_post_id, timestamp(unix nano), nonce
+// 旧交易（版本 0）只签名消息内容，点赞只签名目标帖子 ID
+func (tx *Transaction) SignBytes(chainID string) []byte {
+	if tx.Version < TransactionVersionEnvelope {
+		if tx.IsLike {
+			return []byte(tx.TargetPostID)
+		}
+		return []byte(tx.Message)
+	}
+	return crypto.NewEncoder(uint8(tx.Version)).
+		WriteString(transactionSignDomain).
+		WriteString(chainID).
+		WriteString(tx.Sender).
+		WriteString(tx.Receiver).
+		WriteString(string(tx.kind())).
+		WriteString(tx.Message).
+		WriteString(tx.TargetPostID).
+		WriteInt64(tx.Timestamp.UnixNano()).
+		WriteUint64(tx.Nonce).
+		Bytes()
+}
+
+// writeFields 写入交易 ID 之外的公共字段
+func (tx *Transaction) writeFields(e *crypto.Encoder) *crypto.Encoder {
+	return e.
+		WriteString(tx.Sender).
+		WriteString(tx.Receiver).
+		WriteString(tx.Signature).
+		WriteBool(tx.IsLike).
+		WriteInt64(tx.Timestamp.UnixNano()).
+		WriteString(tx.Message).
+		WriteString(tx.TargetPostID)
+}
+
+// writeEnvelope 写入签名信封新增的字段
+func (tx *Transaction) writeEnvelope(e *crypto.Encoder) *crypto.Encoder {
+	return e.
+		WriteInt64(int64(tx.Version)).
+		WriteUint64(tx.Nonce)
+}
@@ -0,0 +1,105 @@
+package blockchain
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"twichain/internal/crypto"
+)
+
+// FeedPost 时间线中的一条帖子，交易池中尚未上链的帖子 Confirmed 为 false 且没有区块索引
+type FeedPost struct {
+	ID         string    `json:"id"`
+	Sender     string    `json:"sender"`
+	Receiver   string    `json:"receiver"`
+	Message    string    `json:"message"`
+	Timestamp  time.Time `json:"timestamp"`
+	BlockIndex int       `json:"block_index,omitempty"`
+	Confirmed  bool      `json:"confirmed"`
+	Edited     bool      `json:"edited"`
+}
+
+// Feed 时间线的一页
+type Feed struct {
+	Address    string     `json:"address"`
+	Posts      []FeedPost `json:"posts"`
+	NextCursor string     `json:"next_cursor,omitempty"` // 还有下一页时用于请求下一页的游标
+}
+
+// GetFeed 返回该账户关注的账户发布的帖子，最新的在前
+// 已上链的帖子从存储的交易索引按游标分页读取；第一页（cursor 为空）还会在最前面附上交易池中的待打包帖子，
+// 待打包帖子计入 limit，超出的部分不返回，上链后再出现在时间线中
+func (bc *Blockchain) GetFeed(address, cursor string, limit int) (*Feed, error) {
+	if !crypto.ValidateAddress(address) {
+		return nil, fmt.Errorf("invalid address format - must be 256-bit hex string")
+	}
+	limit = pageLimit(limit)
+
+	beforeBlock, beforeTx := math.MaxInt, math.MaxInt
+	if cursor != "" {
+		if _, err := fmt.Sscanf(cursor, "%d:%d", &beforeBlock, &beforeTx); err != nil {
+			return nil, fmt.Errorf("invalid cursor %q", cursor)
+		}
+	}
+
+	bc.mu.RLock()
+	defer bc.mu.RUnlock()
+
+	feed := &Feed{Address: address, Posts: []FeedPost{}}
+	if cursor == "" {
+		pending := bc.pendingFeed(address)
+		feed.Posts = pending[:min(len(pending), limit)]
+	}
+	remaining := limit - len(feed.Posts)
+
+	// 多取一条用于判断是否还有下一页
+	posts, err := bc.storage.GetFeed(address, beforeBlock, beforeTx, remaining+1)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read feed: %v", err)
+	}
+	if len(posts) > remaining {
+		// 待打包帖子占满第一页时，下一页从链顶之后开始读取全部已上链帖子
+		feed.NextCursor = fmt.Sprintf("%d:0", len(bc.Chain)+1)
+		if remaining > 0 {
+			last := posts[remaining-1]
+			feed.NextCursor = fmt.Sprintf("%d:%d", last.BlockIndex, last.TxIndex)
+		}
+		posts = posts[:remaining]
+	}
+	for _, post := range posts {
+		feed.Posts = append(feed.Posts, FeedPost{
+			ID:         post.ID,
+			Sender:     post.Sender,
+			Receiver:   post.Receiver,
+			Message:    post.Message,
+			Timestamp:  post.Timestamp,
+			BlockIndex: post.BlockIndex,
+			Confirmed:  true,
+			Edited:     post.Edited,
+		})
+	}
+	return feed, nil
+}
+
+// pendingFeed 返回交易池中该账户已确认关注的账户的待打包帖子，按时间戳倒序，调用方需持有锁
+func (bc *Blockchain) pendingFeed(address string) []FeedPost {
+	posts := []FeedPost{}
+	for _, tx := range bc.pool.Select(0) {
+		if tx.kind() != KindPost || !bc.state.following(address, tx.Sender) {
+			continue
+		}
+		posts = append(posts, FeedPost{
+			ID:        tx.ID,
+			Sender:    tx.Sender,
+			Receiver:  tx.Receiver,
+			Message:   tx.Message,
+			Timestamp: tx.Timestamp,
+		})
+	}
+	sort.SliceStable(posts, func(i, j int) bool {
+		return posts[i].Timestamp.After(posts[j].Timestamp)
+	})
+	return posts
+}
@@ -0,0 +1,164 @@
+package blockchain
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 已见交易的记录保留时间和数量上限
+const (
+	seenTransactionTTL   = 30 * time.Minute
+	seenTransactionLimit = 100000
+)
+
+// 向单个节点转发交易的超时时间，避免离线节点阻塞整个广播队列
+const announceTimeout = 5 * time.Second
+
+// ErrTransactionSeen 交易最近已经处理过，不再重复验证和转发
+var ErrTransactionSeen = errors.New("transaction already seen")
+
+// seenCache 记录最近处理过的交易 ID，防止交易在节点之间来回转发
+type seenCache struct {
+	mu      sync.Mutex
+	ttl     time.Duration
+	limit   int
+	entries map[string]time.Time
+}
+
+func newSeenCache(ttl time.Duration, limit int) *seenCache {
+	return &seenCache{
+		ttl:     ttl,
+		limit:   limit,
+		entries: make(map[string]time.Time),
+	}
+}
+
+// markSeen 记录交易 ID，已经记录过时返回 false
+func (c *seenCache) markSeen(id string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	if seenAt, ok := c.entries[id]; ok && now.Sub(seenAt) < c.ttl {
+		return false
+	}
+
+	if len(c.entries) >= c.limit {
+		for key, seenAt := range c.entries {
+			if now.Sub(seenAt) >= c.ttl {
+				delete(c.entries, key)
+			}
+		}
+		// 仍然超出上限时清空，最坏情况只是多转发一轮
+		if len(c.entries) >= c.limit {
+			c.entries = make(map[string]time.Time)
+		}
+	}
+
+	c.entries[id] = now
+	return true
+}
+
+// forget 删除交易 ID 的记录
+func (c *seenCache) forget(id string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delete(c.entries, id)
+}
+
+// ReceiveTransaction 处理其他节点转发的交易：重新完整验证后加入交易池并继续转发
+// 保留交易原有的 ID 和时间戳，最近已处理过的交易返回 ErrTransactionSeen
+func (bc *Blockchain) ReceiveTransaction(tx Transaction) error {
+	if !bc.seen.markSeen(tx.ID) {
+		return ErrTransactionSeen
+	}
+
+	if _, err := bc.addPendingTransaction(tx); err != nil {
+		// 被拒绝的交易不会被转发，允许之后再次投递（例如补齐了缺失的序号）
+		bc.seen.forget(tx.ID)
+		return err
+	}
+	// 加入交易池时已经排队转发给其他节点
+	return nil
+}
+
+// announceQueue 待广播交易队列，加入交易池时在同一把锁内入队，不会阻塞
+// 所有交易由 announceLoop 按入队顺序逐个广播，同一发送者的序号 n 总是先于 n+1 到达其他节点，
+// 避免后者因序号不连续被拒绝
+type announceQueue struct {
+	mu     sync.Mutex
+	items  []Transaction
+	signal chan struct{}
+}
+
+func newAnnounceQueue() *announceQueue {
+	return &announceQueue{signal: make(chan struct{}, 1)}
+}
+
+// push 把交易加入队尾并唤醒广播协程
+func (q *announceQueue) push(tx Transaction) {
+	q.mu.Lock()
+	q.items = append(q.items, tx)
+	q.mu.Unlock()
+
+	select {
+	case q.signal <- struct{}{}:
+	default:
+	}
+}
+
+// take 取出队列中的全部交易
+func (q *announceQueue) take() []Transaction {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	items := q.items
+	q.items = nil
+	return items
+}
+
+// announceLoop 按入队顺序依次广播交易
+func (bc *Blockchain) announceLoop() {
+	for range bc.announcements.signal {
+		for _, tx := range bc.announcements.take() {
+			bc.AnnounceTransaction(tx)
+		}
+	}
+}
+
+// AnnounceTransaction 将待打包交易广播给所有已知节点
+func (bc *Blockchain) AnnounceTransaction(tx Transaction) {
+	nodes, err := bc.storage.GetAllNodes()
+	if err != nil {
+		log.Printf("Failed to get nodes: %v", err)
+		return
+	}
+
+	jsonData, err := json.Marshal(tx)
+	if err != nil {
+		log.Printf("Failed to marshal transaction %s: %v", tx.ID, err)
+		return
+	}
+
+	client := &http.Client{Timeout: announceTimeout}
+	for _, node := range nodes {
+		url := fmt.Sprintf("http://%s/transactions/receive", node)
+		resp, err := client.Post(url, "application/json", bytes.NewBuffer(jsonData))
+		if err != nil {
+			if strings.Contains(err.Error(), "connection refused") {
+				log.Printf("Node %s appears to be offline, removing...", node)
+				bc.removeNode(node)
+			}
+			continue
+		}
+		resp.Body.Close()
+	}
+}
@@ -0,0 +1,38 @@
+package blockchain
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+)
+
+// 广播协程删除离线节点时，ResolveConflicts 等读取方可能同时在读锁内遍历节点列表
+// 与 go test -race 一起运行时可以发现未加锁的修改
+func TestRemoveNodeConcurrentWithReaders(t *testing.T) {
+	bc := newTestBlockchain(t)
+	for i := 0; i < 20; i++ {
+		bc.Nodes[fmt.Sprintf("127.0.0.1:%d", 9000+i)] = true
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		node := fmt.Sprintf("127.0.0.1:%d", 9000+i)
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			bc.removeNode(node)
+		}()
+		go func() {
+			defer wg.Done()
+			bc.mu.RLock()
+			for range bc.Nodes {
+			}
+			bc.mu.RUnlock()
+		}()
+	}
+	wg.Wait()
+
+	if len(bc.Nodes) != 0 {
+		t.Errorf("len(Nodes) = %d after removing every node, want 0", len(bc.Nodes))
+	}
+}
@@ -0,0 +1,152 @@
+package blockchain
+
+import (
+	"fmt"
+)
+
+// Kind 交易类型，参与签名
+type Kind string
+
+// 已注册的交易类型
+const (
+	KindPost     Kind = "post"     // 发帖：Message 为内容
+	KindComment  Kind = "comment"  // 评论：TargetPostID 为被评论的交易，Message 为内容
+	KindLike     Kind = "like"     // 点赞：TargetPostID 为被点赞的帖子
+	KindUnlike   Kind = "unlike"   // 取消点赞：TargetPostID 为被点赞的帖子
+	KindFollow   Kind = "follow"   // 关注：Receiver 为被关注者
+	KindUnfollow Kind = "unfollow" // 取消关注：Receiver 为被关注者
+	KindProfile  Kind = "profile"  // 更新资料：Message 为 JSON 资料，见 Profile
+	KindDelete   Kind = "delete"   // 删除：TargetPostID 为被删除的帖子
+	KindEdit     Kind = "edit"     // 编辑：TargetPostID 为被编辑的帖子，Message 为新内容
+)
+
+// kindValidator 验证某一类型交易的载荷
+type kindValidator func(tx *Transaction) error
+
+// kindValidators 交易类型注册表
+var kindValidators = map[Kind]kindValidator{}
+
+// registerKind 注册交易类型及其载荷验证函数
+func registerKind(kind Kind, validate kindValidator) {
+	if _, ok := kindValidators[kind]; ok {
+		panic(fmt.Sprintf("transaction kind %q registered twice", kind))
+	}
+	kindValidators[kind] = validate
+}
+
+func init() {
+	registerKind(KindPost, validatePost)
+	registerKind(KindComment, validateComment)
+	registerKind(KindLike, validateLike)
+	registerKind(KindUnlike, validateUnlike)
+	registerKind(KindFollow, validateFollow)
+	registerKind(KindUnfollow, validateFollow)
+	registerKind(KindProfile, validateProfile)
+	registerKind(KindDelete, validateDelete)
+	registerKind(KindEdit, validateEdit)
+}
+
+// validateKind 按交易类型验证载荷，旧交易只能是由 IsLike 和 TargetPostID 推导出的发帖、评论或点赞
+func validateKind(tx *Transaction) error {
+	kind := tx.kind()
+	if tx.Version < TransactionVersionEnvelope && tx.Kind != "" {
+		return fmt.Errorf("legacy transactions cannot carry an explicit kind")
+	}
+
+	validate, ok := kindValidators[kind]
+	if !ok {
+		return fmt.Errorf("unknown transaction kind %q", kind)
+	}
+	if tx.IsLike != (kind == KindLike) {
+		return fmt.Errorf("is_like must be set exactly for %s transactions", KindLike)
+	}
+	if err := validate(tx); err != nil {
+		return fmt.Errorf("invalid %s transaction: %v", kind, err)
+	}
+	return nil
+}
+
+func validatePost(tx *Transaction) error {
+	if tx.Message == "" {
+		return fmt.Errorf("message is required")
+	}
+	if tx.TargetPostID != "" {
+		return fmt.Errorf("target post ID must be empty")
+	}
+	return nil
+}
+
+func validateComment(tx *Transaction) error {
+	if tx.Message == "" {
+		return fmt.Errorf("message is required")
+	}
+	if tx.TargetPostID == "" {
+		return fmt.Errorf("target post ID is required")
+	}
+	return nil
+}
+
+func validateLike(tx *Transaction) error {
+	if tx.TargetPostID == "" {
+		return fmt.Errorf("target post ID is required")
+	}
+	return nil
+}
+
+func validateUnlike(tx *Transaction) error {
+	if tx.TargetPostID == "" {
+		return fmt.Errorf("target post ID is required")
+	}
+	if tx.Message != "" {
+		return fmt.Errorf("message must be empty")
+	}
+	return nil
+}
+
+// validateFollow 同时用于关注和取消关注
+func validateFollow(tx *Transaction) error {
+	if tx.Receiver == tx.Sender {
+		return fmt.Errorf("cannot follow yourself")
+	}
+	if tx.TargetPostID != "" || tx.Message != "" {
+		return fmt.Errorf("message and target post ID must be empty")
+	}
+	return nil
+}
+
+func validateProfile(tx *Transaction) error {
+	if tx.Receiver != tx.Sender {
+		return fmt.Errorf("receiver must be the sender")
+	}
+	if tx.TargetPostID != "" {
+		return fmt.Errorf("target post ID must be empty")
+	}
+	_, err := parseProfile(tx.Message)
+	return err
+}
+
+func validateDelete(tx *Transaction) error {
+	if tx.Receiver != tx.Sender {
+		return fmt.Errorf("receiver must be the sender")
+	}
+	if tx.TargetPostID == "" {
+		return fmt.Errorf("target post ID is required")
+	}
+	if tx.Message != "" {
+		return fmt.Errorf("message must be empty")
+	}
+	return nil
+}
+
+func validateEdit(tx *Transaction) error {
+	if tx.Receiver != tx.Sender {
+		return fmt.Errorf("receiver must be the sender")
+	}
+	if tx.TargetPostID == "" {
+		return fmt.Errorf("target post ID is required")
+	}
+	if tx.Message == "" {
+		return fmt.Errorf("message is required")
+	}
+	return nil
+}
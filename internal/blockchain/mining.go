@@ -0,0 +1,90 @@
+package blockchain
+
+import (
+	"context"
+	"time"
+
+	"twichain/internal/config"
+	"twichain/internal/consensus"
+)
+
+// 未配置出块间隔时使用的默认值
+const defaultBlockInterval = time.Minute
+
+// MiningPolicy 出块策略
+type MiningPolicy struct {
+	BlockInterval        time.Duration // 定时出块间隔
+	MempoolThreshold     int           // 交易池达到该数量时立即出块，0 表示不启用
+	MaxBlockTransactions int           // 每个区块最多打包的交易数，0 表示不限制
+	EmptyBlocks          bool          // 没有交易时是否按间隔出心跳空块
+}
+
+// newMiningPolicy 从配置创建出块策略
+func newMiningPolicy(cfg *config.Config) MiningPolicy {
+	policy := MiningPolicy{
+		BlockInterval:        time.Duration(cfg.Mining.BlockInterval) * time.Second,
+		MempoolThreshold:     cfg.Mining.MempoolThreshold,
+		MaxBlockTransactions: cfg.Mining.MaxBlockTransactions,
+		EmptyBlocks:          cfg.Mining.EmptyBlocks,
+	}
+	if policy.BlockInterval <= 0 {
+		policy.BlockInterval = defaultBlockInterval
+	}
+	return policy
+}
+
+// MiningStatus 挖矿状态
+type MiningStatus struct {
+	Engine              string  `json:"engine"`
+	HashRate            float64 `json:"hash_rate"` // 最近一次工作量证明搜索的算力（次/秒）
+	Workers             int     `json:"workers"`
+	PendingTransactions int     `json:"pending_transactions"`
+	ChainLength         int     `json:"chain_length"`
+}
+
+// GetMiningStatus 返回当前挖矿状态，非工作量证明引擎的算力为 0
+func (bc *Blockchain) GetMiningStatus() MiningStatus {
+	bc.mu.RLock()
+	status := MiningStatus{
+		Engine:              bc.engine.Name(),
+		PendingTransactions: bc.pool.Len(),
+		ChainLength:         len(bc.Chain),
+	}
+	bc.mu.RUnlock()
+
+	if pow, ok := bc.engine.(*consensus.ProofOfWork); ok {
+		status.HashRate = pow.HashRate()
+		status.Workers = pow.Workers()
+	}
+	return status
+}
+
+// advanceTip 链顶变化时取消正在进行的挖矿，调用方需持有写锁
+func (bc *Blockchain) advanceTip() {
+	bc.tipCancel()
+	bc.tipCtx, bc.tipCancel = context.WithCancel(context.Background())
+}
+
+// thresholdReached 判断交易池是否达到立即出块的数量，调用方需持有锁
+func (bc *Blockchain) thresholdReached() bool {
+	return bc.policy.MempoolThreshold > 0 && bc.pool.Len() >= bc.policy.MempoolThreshold
+}
+
+// signalMining 通知挖矿协程立即出块，已有待处理的通知时直接返回
+func (bc *Blockchain) signalMining() {
+	select {
+	case bc.mineSignal <- struct{}{}:
+	default:
+	}
+}
+
+// selectTransactions 按策略从交易池中选出待打包的交易，调用方需持有锁
+// 先跳过序号不连续等无法在链顶状态上执行的交易，再按区块交易数上限截取，
+// 避免排在前面的无效交易占满区块，使后面的有效交易一直无法打包
+func (bc *Blockchain) selectTransactions() []Transaction {
+	transactions := bc.state.executable(bc.pool.Select(0))
+	if max := bc.policy.MaxBlockTransactions; max > 0 && len(transactions) > max {
+		transactions = transactions[:max]
+	}
+	return transactions
+}
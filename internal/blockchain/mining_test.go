@@ -0,0 +1,41 @@
+package blockchain
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMineSkipsUnexecutableTransactions(t *testing.T) {
+	bc := newTestBlockchain(t)
+	alice := newTestAccount(1)
+
+	// 序号 2 缺少序号 1，无法在链顶状态上执行
+	if err := bc.pool.Add(alice.post(t, 2, "gap")); err != nil {
+		t.Fatalf("pool.Add: %v", err)
+	}
+	bc.Mine()
+	if n := bc.GetChainLength(); n != 1 {
+		t.Errorf("chain length after Mine = %d, want 1: no block should be sealed for unexecutable transactions", n)
+	}
+}
+
+func TestSelectTransactionsCapsAfterFiltering(t *testing.T) {
+	bc := newTestBlockchain(t)
+	bc.policy.MaxBlockTransactions = 1
+	alice, bob := newTestAccount(1), newTestAccount(2)
+
+	// alice 的序号 2 和 3 缺少序号 1，时间戳排在 bob 的有效交易之前
+	gap, gap2 := alice.post(t, 2, "gap"), alice.post(t, 3, "gap")
+	valid := bob.post(t, 1, "valid")
+	valid.Timestamp = gap2.Timestamp.Add(time.Second)
+	for _, tx := range []Transaction{gap, gap2, valid} {
+		if err := bc.pool.Add(tx); err != nil {
+			t.Fatalf("pool.Add(%s): %v", tx.ID, err)
+		}
+	}
+
+	selected := bc.selectTransactions()
+	if len(selected) != 1 || selected[0].ID != valid.ID {
+		t.Errorf("selectTransactions = %d transactions, want only the executable one", len(selected))
+	}
+}
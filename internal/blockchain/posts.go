@@ -0,0 +1,105 @@
+package blockchain
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"twichain/internal/storage"
+)
+
+// LikeCount 帖子的点赞数
+type LikeCount struct {
+	PostID string `json:"post_id"`
+	Likes  int    `json:"likes"`
+}
+
+// GetLikeCount 从存储中增量维护的点赞索引读取帖子的点赞数
+func (bc *Blockchain) GetLikeCount(postID string) (*LikeCount, error) {
+	bc.mu.RLock()
+	defer bc.mu.RUnlock()
+
+	if _, ok := bc.state.post(postID); !ok {
+		return nil, fmt.Errorf("post not found: %s", postID)
+	}
+
+	likes, err := bc.storage.GetLikeCount(postID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read like count: %v", err)
+	}
+	return &LikeCount{PostID: postID, Likes: likes}, nil
+}
+
+// PostVersion 帖子内容的一个版本，第一个版本是原始帖子
+type PostVersion struct {
+	ID         string    `json:"id"` // 原始帖子或编辑交易的 ID
+	Message    string    `json:"message"`
+	Timestamp  time.Time `json:"timestamp"`
+	BlockIndex int       `json:"block_index"`
+}
+
+// PostHistory 帖子的编辑历史，帖子被删除后只返回墓碑，不返回内容
+type PostHistory struct {
+	PostID    string                 `json:"post_id"`
+	Sender    string                 `json:"sender"`
+	Kind      Kind                   `json:"kind"`
+	Deleted   bool                   `json:"deleted"`
+	Tombstone *storage.TombstoneData `json:"tombstone,omitempty"`
+	Versions  []PostVersion          `json:"versions"`
+}
+
+// GetPostHistory 从存储的编辑和墓碑索引读取已上链帖子或评论的编辑历史
+func (bc *Blockchain) GetPostHistory(postID string) (*PostHistory, error) {
+	bc.mu.RLock()
+	defer bc.mu.RUnlock()
+
+	post, blockIndex, err := bc.storage.GetTransaction(postID)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("post not found: %s", postID)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read post: %v", err)
+	}
+	if kind := Kind(post.Kind); kind != KindPost && kind != KindComment {
+		return nil, fmt.Errorf("transaction %s is not a post or comment", postID)
+	}
+
+	history := &PostHistory{
+		PostID:   post.ID,
+		Sender:   post.Sender,
+		Kind:     Kind(post.Kind),
+		Versions: []PostVersion{},
+	}
+
+	tombstone, err := bc.storage.GetTombstone(postID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read tombstone: %v", err)
+	}
+	if tombstone != nil {
+		// 删除后隐藏所有版本的内容，原始区块数据仍可通过 /chain 审计
+		history.Deleted = true
+		history.Tombstone = tombstone
+		return history, nil
+	}
+
+	edits, err := bc.storage.GetPostEdits(postID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read edits: %v", err)
+	}
+
+	history.Versions = append(history.Versions, PostVersion{
+		ID:         post.ID,
+		Message:    post.Message,
+		Timestamp:  post.Timestamp,
+		BlockIndex: blockIndex,
+	})
+	for _, edit := range edits {
+		history.Versions = append(history.Versions, PostVersion{
+			ID:         edit.EditID,
+			Message:    edit.Message,
+			Timestamp:  edit.Timestamp,
+			BlockIndex: edit.BlockIndex,
+		})
+	}
+	return history, nil
+}
@@ -0,0 +1,127 @@
+package blockchain
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"unicode/utf8"
+
+	"twichain/internal/crypto"
+)
+
+// 资料字段的长度限制
+const (
+	maxDisplayNameLength = 50  // 字符数
+	maxBioLength         = 280 // 字符数
+	maxProfileLinks      = 5
+	maxLinkLength        = 256
+)
+
+// Profile 资料交易的载荷，每次更新都是完整资料，以最后上链的一次为准
+type Profile struct {
+	DisplayName string   `json:"display_name,omitempty"`
+	Bio         string   `json:"bio,omitempty"`
+	AvatarHash  string   `json:"avatar_hash,omitempty"` // 头像内容的 SHA-256 十六进制哈希，图片本身不上链
+	Links       []string `json:"links,omitempty"`
+}
+
+// parseProfile 严格解析资料载荷，拒绝未知字段和超出限制的字段
+func parseProfile(message string) (*Profile, error) {
+	decoder := json.NewDecoder(bytes.NewReader([]byte(message)))
+	decoder.DisallowUnknownFields()
+
+	var profile Profile
+	if err := decoder.Decode(&profile); err != nil {
+		return nil, fmt.Errorf("message must be a JSON profile object: %v", err)
+	}
+	if decoder.More() {
+		return nil, fmt.Errorf("message must contain a single JSON object")
+	}
+	if err := profile.validate(); err != nil {
+		return nil, err
+	}
+	return &profile, nil
+}
+
+// validate 检查资料字段的格式和长度
+func (p *Profile) validate() error {
+	if utf8.RuneCountInString(p.DisplayName) > maxDisplayNameLength {
+		return fmt.Errorf("display name exceeds %d characters", maxDisplayNameLength)
+	}
+	if utf8.RuneCountInString(p.Bio) > maxBioLength {
+		return fmt.Errorf("bio exceeds %d characters", maxBioLength)
+	}
+	if p.AvatarHash != "" {
+		if hash, err := hex.DecodeString(p.AvatarHash); err != nil || len(hash) != 32 {
+			return fmt.Errorf("avatar hash must be a SHA-256 hex digest")
+		}
+	}
+	if len(p.Links) > maxProfileLinks {
+		return fmt.Errorf("at most %d links are allowed", maxProfileLinks)
+	}
+	for _, link := range p.Links {
+		if len(link) > maxLinkLength {
+			return fmt.Errorf("link exceeds %d bytes", maxLinkLength)
+		}
+		u, err := url.Parse(link)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("invalid link %q: must be an absolute http or https URL", link)
+		}
+	}
+	return nil
+}
+
+// User 账户的资料和统计
+type User struct {
+	Address           string   `json:"address"`
+	Profile           *Profile `json:"profile"`                       // 没有资料交易时为 null
+	ProfileUpdateID   string   `json:"profile_update_id,omitempty"`   // 生效的资料交易 ID
+	ProfileBlockIndex int      `json:"profile_block_index,omitempty"` // 生效的资料交易所在区块
+	Posts             int      `json:"posts"`                         // 未删除的帖子数
+	Comments          int      `json:"comments"`                      // 未删除的评论数
+	Followers         int      `json:"followers"`
+	Following         int      `json:"following"`
+	LikesReceived     int      `json:"likes_received"` // 帖子和评论当前收到的点赞总数
+	LikesGiven        int      `json:"likes_given"`    // 当前点赞的帖子和评论数
+}
+
+// GetUser 从存储的资料和统计索引读取账户已上链的资料和统计
+func (bc *Blockchain) GetUser(address string) (*User, error) {
+	if !crypto.ValidateAddress(address) {
+		return nil, fmt.Errorf("invalid address format - must be 256-bit hex string")
+	}
+
+	bc.mu.RLock()
+	defer bc.mu.RUnlock()
+
+	stats, err := bc.storage.GetUserStats(address)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read user stats: %v", err)
+	}
+	user := &User{
+		Address:       address,
+		Posts:         stats.Posts,
+		Comments:      stats.Comments,
+		Followers:     stats.Followers,
+		Following:     stats.Following,
+		LikesReceived: stats.LikesReceived,
+		LikesGiven:    stats.LikesGiven,
+	}
+
+	record, err := bc.storage.GetProfile(address)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read profile: %v", err)
+	}
+	if record != nil {
+		profile, err := parseProfile(record.Message)
+		if err != nil {
+			return nil, fmt.Errorf("stored profile %s is invalid: %v", record.UpdateID, err)
+		}
+		user.Profile = profile
+		user.ProfileUpdateID = record.UpdateID
+		user.ProfileBlockIndex = record.BlockIndex
+	}
+	return user, nil
+}
@@ -0,0 +1,54 @@
+package blockchain
+
+import (
+	"fmt"
+
+	"twichain/internal/crypto"
+)
+
+// TransactionProof 交易的 Merkle 包含性证明，轻客户端只需区块头即可验证交易存在
+type TransactionProof struct {
+	TransactionID   string                   `json:"transaction_id"`
+	TransactionHash string                   `json:"transaction_hash"`
+	BlockIndex      int                      `json:"block_index"`
+	BlockHash       string                   `json:"block_hash"`
+	MerkleRoot      string                   `json:"merkle_root"`
+	Proof           []crypto.MerkleProofStep `json:"proof"`
+}
+
+// GetTransactionProof 查找已上链的交易并生成其 Merkle 包含性证明
+func (bc *Blockchain) GetTransactionProof(txID string) (*TransactionProof, error) {
+	bc.mu.RLock()
+	defer bc.mu.RUnlock()
+
+	// 从链尾开始查找，新交易更常被查询
+	for i := len(bc.Chain) - 1; i >= 0; i-- {
+		block := bc.Chain[i]
+		for j := range block.Transactions {
+			if block.Transactions[j].ID != txID {
+				continue
+			}
+
+			if block.MerkleRoot == "" {
+				return nil, fmt.Errorf("block %d has no merkle root", block.Index)
+			}
+
+			hashes := transactionHashes(block.Transactions)
+			proof, err := crypto.MerkleProof(hashes, j)
+			if err != nil {
+				return nil, err
+			}
+
+			return &TransactionProof{
+				TransactionID:   txID,
+				TransactionHash: hashes[j],
+				BlockIndex:      block.Index,
+				BlockHash:       block.Hash(),
+				MerkleRoot:      block.MerkleRoot,
+				Proof:           proof,
+			}, nil
+		}
+	}
+
+	return nil, fmt.Errorf("transaction not found: %s", txID)
+}
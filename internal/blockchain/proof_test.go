@@ -0,0 +1,60 @@
+package blockchain
+
+import (
+	"fmt"
+	"testing"
+
+	"twichain/internal/crypto"
+)
+
+func TestGetTransactionProof(t *testing.T) {
+	// 覆盖单笔交易的区块、完全二叉树以及叶子数为奇数的区块
+	for _, n := range []int{1, 2, 3, 5, 8} {
+		transactions := make([]Transaction, n)
+		for i := range transactions {
+			transactions[i] = Transaction{
+				ID:      fmt.Sprintf("tx-%d-%d", n, i),
+				Sender:  "alice",
+				Message: fmt.Sprintf("post %d", i),
+			}
+		}
+		block := &Block{Index: 2, PrevHash: "parent", Transactions: transactions, MerkleRoot: computeMerkleRoot(transactions)}
+		bc := &Blockchain{Chain: []*Block{block}}
+
+		for i := range transactions {
+			proof, err := bc.GetTransactionProof(transactions[i].ID)
+			if err != nil {
+				t.Fatalf("%d transactions: GetTransactionProof(%d): %v", n, i, err)
+			}
+			if proof.BlockIndex != block.Index || proof.BlockHash != block.Hash() || proof.MerkleRoot != block.MerkleRoot {
+				t.Errorf("%d transactions: proof %d points to block %d %s with root %s",
+					n, i, proof.BlockIndex, proof.BlockHash, proof.MerkleRoot)
+			}
+			if proof.TransactionHash != transactions[i].Hash() {
+				t.Errorf("%d transactions: proof %d has transaction hash %s, want %s",
+					n, i, proof.TransactionHash, transactions[i].Hash())
+			}
+			if !crypto.VerifyMerkleProof(proof.TransactionHash, block.MerkleRoot, proof.Proof) {
+				t.Errorf("%d transactions: proof %d does not verify against the block merkle root", n, i)
+			}
+			// 证明不能用于区块中的其他交易
+			if other := transactions[(i+1)%n]; n > 1 && crypto.VerifyMerkleProof(other.Hash(), block.MerkleRoot, proof.Proof) {
+				t.Errorf("%d transactions: proof %d verifies transaction %s", n, i, other.ID)
+			}
+		}
+	}
+}
+
+func TestGetTransactionProofErrors(t *testing.T) {
+	transactions := []Transaction{{ID: "tx", Sender: "alice", Message: "hello"}}
+	bc := &Blockchain{Chain: []*Block{
+		{Index: 1, Transactions: transactions},
+	}}
+
+	if _, err := bc.GetTransactionProof("tx"); err == nil {
+		t.Errorf("GetTransactionProof succeeded for a block without merkle root")
+	}
+	if _, err := bc.GetTransactionProof("missing"); err == nil {
+		t.Errorf("GetTransactionProof succeeded for an unknown transaction")
+	}
+}
@@ -0,0 +1,166 @@
+package blockchain
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"math/big"
+	"net/http"
+	"time"
+
+	"twichain/internal/storage"
+)
+
+// 拉取其他节点区块链时的超时时间
+const chainFetchTimeout = 10 * time.Second
+
+// ResolveConflicts 共识算法：拉取所有已知节点的链，用工作量最大的有效链替换本地链
+// 返回本地链是否被替换
+func (bc *Blockchain) ResolveConflicts() (bool, error) {
+	// 同一时间只允许一次分叉处理
+	if !bc.resolving.TryLock() {
+		return false, nil
+	}
+	defer bc.resolving.Unlock()
+
+	bc.mu.RLock()
+	nodes := make([]string, 0, len(bc.Nodes))
+	for node := range bc.Nodes {
+		nodes = append(nodes, node)
+	}
+	bestWork := bc.chainWork(bc.Chain)
+	genesisHash := bc.Chain[0].Hash()
+	bc.mu.RUnlock()
+
+	var bestChain []*Block
+	var bestState *chainState
+	for _, node := range nodes {
+		chain, err := fetchChain(node)
+		if err != nil {
+			log.Printf("Failed to fetch chain from node %s: %v", node, err)
+			continue
+		}
+
+		work := bc.chainWork(chain)
+		if work.Cmp(bestWork) <= 0 {
+			continue
+		}
+
+		state, err := bc.replayChain(chain)
+		if err != nil {
+			log.Printf("Rejected chain from node %s: %v", node, err)
+			continue
+		}
+		if chain[0].Hash() != genesisHash {
+			log.Printf("Rejected chain from node %s: genesis block mismatch", node)
+			continue
+		}
+
+		bestChain = chain
+		bestState = state
+		bestWork = work
+	}
+
+	if bestChain == nil {
+		return false, nil
+	}
+
+	return bc.replaceChain(bestChain, bestState)
+}
+
+// resolveInBackground 供异步触发冲突处理时使用，错误只记录日志
+func (bc *Blockchain) resolveInBackground() {
+	replaced, err := bc.ResolveConflicts()
+	if err != nil {
+		log.Printf("Failed to resolve conflicts: %v", err)
+		return
+	}
+	if replaced {
+		log.Printf("Local chain replaced by a chain with more work")
+	}
+}
+
+// fetchChain 从指定节点获取完整区块链
+func fetchChain(node string) ([]*Block, error) {
+	client := &http.Client{Timeout: chainFetchTimeout}
+	resp, err := client.Get(fmt.Sprintf("http://%s/chain", node))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status: %s", resp.Status)
+	}
+
+	var result struct {
+		Chain  []*Block `json:"chain"`
+		Length int      `json:"length"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to decode chain: %v", err)
+	}
+	if len(result.Chain) != result.Length {
+		return nil, fmt.Errorf("chain length mismatch: got %d blocks, reported %d", len(result.Chain), result.Length)
+	}
+	// 计算工作量时会读取每个区块头，需要在验证整条链之前排除空区块
+	for i, block := range result.Chain {
+		if block == nil {
+			return nil, fmt.Errorf("block at position %d is missing", i)
+		}
+	}
+
+	return result.Chain, nil
+}
+
+// replaceChain 用新链替换本地链，只重写分叉点之后的区块，state 为新链重放后的链上状态
+func (bc *Blockchain) replaceChain(newChain []*Block, state *chainState) (bool, error) {
+	bc.mu.Lock()
+	defer bc.mu.Unlock()
+
+	// 拉取期间本地链可能已经增长，需要重新比较
+	if bc.chainWork(newChain).Cmp(bc.chainWork(bc.Chain)) <= 0 {
+		return false, nil
+	}
+
+	if len(bc.Chain) > 0 && bc.Chain[0].Hash() != newChain[0].Hash() {
+		return false, fmt.Errorf("genesis block mismatch")
+	}
+
+	// 找到分叉点：第一个哈希不同的区块
+	fork := 0
+	for fork < len(bc.Chain) && fork < len(newChain) &&
+		bc.Chain[fork].Hash() == newChain[fork].Hash() {
+		fork++
+	}
+
+	blocksData := make([]*storage.BlockData, 0, len(newChain)-fork)
+	for _, block := range newChain[fork:] {
+		blocksData = append(blocksData, toBlockData(block))
+	}
+
+	if err := bc.storage.ReplaceBlocksFrom(newChain[fork].Index, blocksData); err != nil {
+		return false, fmt.Errorf("failed to replace blocks: %v", err)
+	}
+
+	log.Printf("Chain replaced from block %d: %d blocks -> %d blocks",
+		newChain[fork].Index, len(bc.Chain), len(newChain))
+	// 先切换到新链的状态，新链已打包的交易从交易池移除，再按新状态检查被丢弃分支中的交易并放回交易池
+	orphaned := bc.Chain[fork:]
+	bc.Chain = newChain
+	bc.state = state
+	bc.removeIncludedTransactions(newChain[fork:])
+	bc.restoreOrphanedTransactions(orphaned)
+	bc.revalidatePool()
+	bc.advanceTip()
+	return true, nil
+}
+
+// chainWork 按共识引擎的分叉选择规则计算链的累计权重
+func (bc *Blockchain) chainWork(chain []*Block) *big.Int {
+	work := new(big.Int)
+	for _, block := range chain {
+		work.Add(work, bc.engine.Weight(block.Header()))
+	}
+	return work
+}
@@ -0,0 +1,124 @@
+package blockchain
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// serveChain 启动返回 chain 的测试节点，并把它登记为 bc 的已知节点
+func serveChain(t *testing.T, bc *Blockchain, chain []*Block) {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"chain":  chain,
+			"length": len(chain),
+		})
+	}))
+	t.Cleanup(server.Close)
+	bc.Nodes[strings.TrimPrefix(server.URL, "http://")] = true
+}
+
+func TestResolveConflictsReplacesWithHeavierChain(t *testing.T) {
+	bc := newTestBlockchain(t)
+	alice, bob, carol := newTestAccount(1), newTestAccount(2), newTestAccount(3)
+
+	post := alice.post(t, 1, "hello")
+	if err := bc.AddBlock(bc.mineTestBlock(t, bc.Chain, post)); err != nil {
+		t.Fatalf("AddBlock(post): %v", err)
+	}
+	common := bc.GetChain()
+
+	// 本地分支只有一个区块，远端分支有两个区块，工作量更大
+	bobPost := bob.post(t, 1, "local")
+	if err := bc.AddBlock(bc.mineTestBlock(t, bc.Chain, bobPost)); err != nil {
+		t.Fatalf("AddBlock(local branch): %v", err)
+	}
+	comment := carol.transaction(t, KindComment, 1, alice.public, "remote", post.ID)
+	remote := append([]*Block(nil), common...)
+	remote = append(remote, bc.mineTestBlock(t, remote, comment))
+	remote = append(remote, bc.mineTestBlock(t, remote, carol.post(t, 2, "remote")))
+	serveChain(t, bc, remote)
+
+	replaced, err := bc.ResolveConflicts()
+	if err != nil || !replaced {
+		t.Fatalf("ResolveConflicts = %v, %v, want true, nil", replaced, err)
+	}
+
+	chain := bc.GetChain()
+	if len(chain) != len(remote) || chain[len(chain)-1].Hash() != remote[len(remote)-1].Hash() {
+		t.Fatalf("local tip does not match the remote chain")
+	}
+	// 链上状态与新链一致：carol 的序号前进，被丢弃分支中的交易不再算作已打包
+	if nonce := bc.state.nonce(carol.public); nonce != 2 {
+		t.Errorf("carol nonce = %d, want 2", nonce)
+	}
+	if _, ok := bc.state.post(comment.ID); !ok {
+		t.Errorf("comment from the new chain is not indexed")
+	}
+	if bc.state.included(bobPost.ID) {
+		t.Errorf("orphaned transaction is still marked as included")
+	}
+	// 存储从分叉点开始被重写
+	if _, _, err := bc.storage.GetTransaction(bobPost.ID); err == nil {
+		t.Errorf("orphaned transaction is still stored")
+	}
+	if _, _, err := bc.storage.GetTransaction(comment.ID); err != nil {
+		t.Errorf("GetTransaction(new chain comment): %v", err)
+	}
+	// 被丢弃分支中仍可执行的交易回到交易池
+	if !bc.pool.Has(bobPost.ID) {
+		t.Errorf("orphaned transaction was not restored to the pool")
+	}
+}
+
+func TestResolveConflictsKeepsChainWhenForkIsInvalid(t *testing.T) {
+	bc := newTestBlockchain(t)
+	alice, bob := newTestAccount(1), newTestAccount(2)
+
+	if err := bc.AddBlock(bc.mineTestBlock(t, bc.Chain, alice.post(t, 1, "hello"))); err != nil {
+		t.Fatalf("AddBlock: %v", err)
+	}
+	local := bc.GetChain()
+
+	// 远端链工作量更大，但评论的目标帖子不存在，执行区块时失败
+	remote := append([]*Block(nil), local...)
+	remote = append(remote, bc.mineTestBlock(t, remote, bob.transaction(t, KindComment, 1, alice.public, "reply", strings.Repeat("0", 64))))
+	remote = append(remote, bc.mineTestBlock(t, remote, bob.post(t, 2, "after")))
+	if err := bc.ValidateChain(remote); err == nil || !strings.Contains(err.Error(), "unknown target post") {
+		t.Fatalf("ValidateChain(remote) = %v, want unknown target post", err)
+	}
+	serveChain(t, bc, remote)
+
+	replaced, err := bc.ResolveConflicts()
+	if err != nil || replaced {
+		t.Fatalf("ResolveConflicts = %v, %v, want false, nil", replaced, err)
+	}
+	chain := bc.GetChain()
+	if len(chain) != len(local) || chain[len(chain)-1].Hash() != local[len(local)-1].Hash() {
+		t.Errorf("local chain changed after rejecting an invalid fork")
+	}
+	if nonce := bc.state.nonce(bob.public); nonce != 0 {
+		t.Errorf("bob nonce = %d after rejecting an invalid fork, want 0", nonce)
+	}
+}
+
+func TestExecuteBlockLeavesStateUnchangedOnFailure(t *testing.T) {
+	bc := newTestBlockchain(t)
+	alice := newTestAccount(1)
+
+	// 第一笔交易有效，第二笔序号不连续，整个区块失败时第一笔也不能生效
+	first := alice.post(t, 1, "first")
+	block := bc.mineTestBlock(t, bc.Chain, first, alice.post(t, 3, "gap"))
+	if _, err := bc.state.executeBlock(block); err == nil || !strings.Contains(err.Error(), "invalid nonce") {
+		t.Fatalf("executeBlock = %v, want invalid nonce", err)
+	}
+	if nonce := bc.state.nonce(alice.public); nonce != 0 {
+		t.Errorf("alice nonce = %d after a failed block, want 0", nonce)
+	}
+	if _, ok := bc.state.post(first.ID); ok || bc.state.included(first.ID) {
+		t.Errorf("transaction from a failed block was applied")
+	}
+}
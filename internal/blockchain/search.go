@@ -0,0 +1,136 @@
+package blockchain
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode"
+
+	"twichain/internal/crypto"
+	"twichain/internal/storage"
+)
+
+// ErrSearchUnavailable 节点未启用 FTS5，见 storage.ErrSearchUnavailable
+var ErrSearchUnavailable = storage.ErrSearchUnavailable
+
+// 一次搜索最多的词和短语数
+const maxSearchTerms = 16
+
+// SearchSort 搜索结果的排序方式
+type SearchSort string
+
+const (
+	SearchSortRelevance SearchSort = "relevance" // bm25 相关度最高的在前
+	SearchSortRecent    SearchSort = "recent"    // 最新上链的在前
+)
+
+// SearchFilter 全文搜索的查询条件和分页
+type SearchFilter struct {
+	Query  string     // 空格分隔的词，"..." 为短语，全部出现才匹配，见 parseSearchTerms
+	Author string     // 只搜索该地址发布的内容，为空表示不限制
+	Since  time.Time  // 只返回此时间及之后发布的内容，零值表示不限制
+	Until  time.Time  // 只返回此时间之前发布的内容，零值表示不限制
+	Sort   SearchSort // 为空时按 relevance
+	Limit  int
+	Offset int
+}
+
+// SearchPage 搜索结果的一页
+type SearchPage struct {
+	Query      string                     `json:"query"`
+	Sort       SearchSort                 `json:"sort"`
+	Results    []storage.SearchResultData `json:"results"`
+	Offset     int                        `json:"offset"`
+	NextOffset int                        `json:"next_offset,omitempty"` // 还有下一页时的偏移量
+}
+
+// Search 在已上链帖子和评论的当前内容中全文搜索，已删除的内容不会被搜到
+func (bc *Blockchain) Search(filter SearchFilter) (*SearchPage, error) {
+	terms, err := parseSearchTerms(filter.Query)
+	if err != nil {
+		return nil, err
+	}
+	if filter.Author != "" && !crypto.ValidateAddress(filter.Author) {
+		return nil, fmt.Errorf("invalid author address format - must be 256-bit hex string")
+	}
+	if filter.Sort == "" {
+		filter.Sort = SearchSortRelevance
+	}
+	if filter.Sort != SearchSortRelevance && filter.Sort != SearchSortRecent {
+		return nil, fmt.Errorf("unknown sort %q: must be %s or %s", filter.Sort, SearchSortRelevance, SearchSortRecent)
+	}
+	if !filter.Since.IsZero() && !filter.Until.IsZero() && !filter.Since.Before(filter.Until) {
+		return nil, fmt.Errorf("since must be before until")
+	}
+	if filter.Offset < 0 {
+		return nil, fmt.Errorf("offset must not be negative")
+	}
+	limit := pageLimit(filter.Limit)
+
+	bc.mu.RLock()
+	defer bc.mu.RUnlock()
+
+	// 多取一条用于判断是否还有下一页
+	results, err := bc.storage.Search(storage.SearchQuery{
+		Terms:  terms,
+		Author: filter.Author,
+		Since:  filter.Since,
+		Until:  filter.Until,
+		Sort:   string(filter.Sort),
+		Limit:  limit + 1,
+		Offset: filter.Offset,
+	})
+	if errors.Is(err, storage.ErrSearchUnavailable) {
+		return nil, err
+	}
+	if err != nil {
+		return nil, fmt.Errorf("search failed: %v", err)
+	}
+
+	page := &SearchPage{
+		Query:   filter.Query,
+		Sort:    filter.Sort,
+		Results: results,
+		Offset:  filter.Offset,
+	}
+	if len(results) > limit {
+		page.Results = results[:limit]
+		page.NextOffset = filter.Offset + limit
+	}
+	return page, nil
+}
+
+// parseSearchTerms 把查询拆分成词和短语：引号内为一个短语（缺少右引号时到结尾为止），其余按空白分隔
+// 每个词或短语都按子串匹配，因此中文可以直接搜索其中的任意片段
+func parseSearchTerms(query string) ([]string, error) {
+	var terms []string
+	var current strings.Builder
+	quoted := false
+	flush := func() {
+		if term := strings.TrimSpace(current.String()); term != "" {
+			terms = append(terms, term)
+		}
+		current.Reset()
+	}
+	for _, r := range query {
+		switch {
+		case r == '"':
+			flush()
+			quoted = !quoted
+		case unicode.IsSpace(r) && !quoted:
+			flush()
+		default:
+			current.WriteRune(r)
+		}
+	}
+	flush()
+
+	if len(terms) == 0 {
+		return nil, fmt.Errorf("search query is required")
+	}
+	if len(terms) > maxSearchTerms {
+		return nil, fmt.Errorf("too many search terms: at most %d are allowed", maxSearchTerms)
+	}
+	return terms, nil
+}
@@ -0,0 +1,92 @@
+package blockchain
+
+import (
+	"fmt"
+
+	"twichain/internal/crypto"
+	"twichain/internal/storage"
+)
+
+// 分页查询每页的默认条数和最大条数
+const (
+	defaultPageLimit = 50
+	maxPageLimit     = 200
+)
+
+// FollowRelation 社交关系列表的类型
+type FollowRelation string
+
+const (
+	FollowersRelation FollowRelation = "followers" // 关注该账户的账户
+	FollowingRelation FollowRelation = "following" // 该账户关注的账户
+	MutualsRelation   FollowRelation = "mutuals"   // 与该账户互相关注的账户
+)
+
+// FollowPage 社交关系列表的一页
+type FollowPage struct {
+	Address    string               `json:"address"`
+	Relation   FollowRelation       `json:"relation"`
+	Followers  int                  `json:"followers"` // 粉丝总数
+	Following  int                  `json:"following"` // 关注总数
+	Accounts   []storage.FollowData `json:"accounts"`
+	Offset     int                  `json:"offset"`
+	NextOffset int                  `json:"next_offset,omitempty"` // 还有下一页时的偏移量
+}
+
+// GetFollows 从存储的社交关系索引分页读取已上链的粉丝、关注或互相关注列表
+func (bc *Blockchain) GetFollows(address string, relation FollowRelation, limit, offset int) (*FollowPage, error) {
+	if !crypto.ValidateAddress(address) {
+		return nil, fmt.Errorf("invalid address format - must be 256-bit hex string")
+	}
+	if offset < 0 {
+		return nil, fmt.Errorf("offset must not be negative")
+	}
+	limit = pageLimit(limit)
+
+	var query func(address string, limit, offset int) ([]storage.FollowData, error)
+	switch relation {
+	case FollowersRelation:
+		query = bc.storage.GetFollowers
+	case FollowingRelation:
+		query = bc.storage.GetFollowing
+	case MutualsRelation:
+		query = bc.storage.GetMutuals
+	default:
+		return nil, fmt.Errorf("unknown follow relation %q", relation)
+	}
+
+	bc.mu.RLock()
+	defer bc.mu.RUnlock()
+
+	followers, following, err := bc.storage.GetFollowCounts(address)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read follow counts: %v", err)
+	}
+	// 多取一条用于判断是否还有下一页
+	accounts, err := query(address, limit+1, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %v", relation, err)
+	}
+
+	page := &FollowPage{
+		Address:   address,
+		Relation:  relation,
+		Followers: followers,
+		Following: following,
+		Accounts:  accounts,
+		Offset:    offset,
+	}
+	if len(accounts) > limit {
+		page.Accounts = accounts[:limit]
+		page.NextOffset = offset + limit
+	}
+	return page, nil
+}
+
+// pageLimit 将请求的每页条数限制在 [1, maxPageLimit]，未指定时使用默认值
+func pageLimit(limit int) int {
+	if limit <= 0 {
+		return defaultPageLimit
+	}
+	return min(limit, maxPageLimit)
+}
@@ -0,0 +1,103 @@
+package blockchain
+
+import (
+	"fmt"
+	"time"
+
+	"twichain/internal/crypto"
+	"twichain/internal/storage"
+)
+
+// MainSpaceName 主空间在 API 中的名称，对应配置的主空间地址
+const MainSpaceName = "main"
+
+// SpaceSort 空间帖子列表的排序方式
+type SpaceSort string
+
+const (
+	SpaceSortRecent SpaceSort = "recent" // 最新发布的在前
+	SpaceSortTop    SpaceSort = "top"    // 点赞最多的在前
+	SpaceSortActive SpaceSort = "active" // 最近有评论的在前
+)
+
+// SpaceFilter 空间帖子列表的排序、时间范围和分页
+type SpaceFilter struct {
+	Sort   SpaceSort // 为空时按 recent
+	Since  time.Time // 只返回此时间及之后发布的帖子，零值表示不限制
+	Until  time.Time // 只返回此时间之前发布的帖子，零值表示不限制
+	Limit  int
+	Offset int
+}
+
+// SpacePage 空间帖子列表的一页
+type SpacePage struct {
+	Space      string                  `json:"space"`
+	Address    string                  `json:"address"`
+	Sort       SpaceSort               `json:"sort"`
+	Posts      []storage.SpacePostData `json:"posts"`
+	Offset     int                     `json:"offset"`
+	NextOffset int                     `json:"next_offset,omitempty"` // 还有下一页时的偏移量
+}
+
+// MainSpace 返回主空间地址
+func (bc *Blockchain) MainSpace() string {
+	return bc.mainSpace
+}
+
+// GetSpacePosts 从存储的交易索引分页读取发往空间的已上链帖子
+// space 为 "main" 时使用配置的主空间地址，也可以直接传入空间地址
+func (bc *Blockchain) GetSpacePosts(space string, filter SpaceFilter) (*SpacePage, error) {
+	address := space
+	if space == MainSpaceName {
+		address = bc.mainSpace
+	}
+	if !crypto.ValidateAddress(address) {
+		return nil, fmt.Errorf("unknown space %q", space)
+	}
+
+	if filter.Sort == "" {
+		filter.Sort = SpaceSortRecent
+	}
+	switch filter.Sort {
+	case SpaceSortRecent, SpaceSortTop, SpaceSortActive:
+	default:
+		return nil, fmt.Errorf("unknown sort %q: must be %s, %s or %s",
+			filter.Sort, SpaceSortRecent, SpaceSortTop, SpaceSortActive)
+	}
+	if !filter.Since.IsZero() && !filter.Until.IsZero() && !filter.Since.Before(filter.Until) {
+		return nil, fmt.Errorf("since must be before until")
+	}
+	if filter.Offset < 0 {
+		return nil, fmt.Errorf("offset must not be negative")
+	}
+	limit := pageLimit(filter.Limit)
+
+	bc.mu.RLock()
+	defer bc.mu.RUnlock()
+
+	// 多取一条用于判断是否还有下一页
+	posts, err := bc.storage.GetSpacePosts(storage.SpaceQuery{
+		Receiver: address,
+		Sort:     string(filter.Sort),
+		Since:    filter.Since,
+		Until:    filter.Until,
+		Limit:    limit + 1,
+		Offset:   filter.Offset,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read space posts: %v", err)
+	}
+
+	page := &SpacePage{
+		Space:   space,
+		Address: address,
+		Sort:    filter.Sort,
+		Posts:   posts,
+		Offset:  filter.Offset,
+	}
+	if len(posts) > limit {
+		page.Posts = posts[:limit]
+		page.NextOffset = filter.Offset + limit
+	}
+	return page, nil
+}
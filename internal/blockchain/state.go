@@ -0,0 +1,267 @@
+package blockchain
+
+import (
+	"fmt"
+)
+
+// chainState 按区块顺序重放得到的链上状态，用于验证依赖历史的交易规则
+// 验证区块时在子状态上试执行，全部通过后再合并到父状态，失败时父状态保持不变
+type chainState struct {
+	parent       *chainState
+	nonces       map[string]uint64     // 发送者 -> 最新确认的序号
+	posts        map[string]postRecord // 帖子和评论的交易 ID -> 作者和类型
+	likes        map[likeKey]bool      // (发送者, 帖子) -> 是否点赞，子状态中 false 表示取消了父状态的点赞
+	follows      map[followKey]bool    // (关注者, 被关注者) -> 是否关注，子状态中 false 表示取消了父状态的关注
+	transactions map[string]bool       // 已打包的交易 ID
+}
+
+// likeKey 点赞状态的键
+type likeKey struct {
+	Sender string
+	PostID string
+}
+
+// followKey 关注状态的键
+type followKey struct {
+	Follower string
+	Followee string
+}
+
+// postRecord 帖子索引中的一条记录
+type postRecord struct {
+	Sender  string
+	Kind    Kind
+	Deleted bool // 已被作者删除，只保留墓碑
+}
+
+// postLookup 按交易 ID 查找帖子或评论
+type postLookup func(id string) (postRecord, bool)
+
+func newChainState() *chainState {
+	return &chainState{
+		nonces:       make(map[string]uint64),
+		posts:        make(map[string]postRecord),
+		likes:        make(map[likeKey]bool),
+		follows:      make(map[followKey]bool),
+		transactions: make(map[string]bool),
+	}
+}
+
+// child 创建读取穿透到当前状态、写入只落在自身的子状态
+func (s *chainState) child() *chainState {
+	child := newChainState()
+	child.parent = s
+	return child
+}
+
+// commit 将子状态的修改合并到父状态
+func (s *chainState) commit() {
+	for sender, nonce := range s.nonces {
+		s.parent.nonces[sender] = nonce
+	}
+	for id, post := range s.posts {
+		s.parent.posts[id] = post
+	}
+	for key, liked := range s.likes {
+		if liked || s.parent.parent != nil {
+			s.parent.likes[key] = liked
+		} else {
+			delete(s.parent.likes, key)
+		}
+	}
+	for key, following := range s.follows {
+		if following || s.parent.parent != nil {
+			s.parent.follows[key] = following
+		} else {
+			delete(s.parent.follows, key)
+		}
+	}
+	for id := range s.transactions {
+		s.parent.transactions[id] = true
+	}
+}
+
+// nonce 返回发送者最新确认的序号，没有确认交易时为 0
+func (s *chainState) nonce(sender string) uint64 {
+	for state := s; state != nil; state = state.parent {
+		if nonce, ok := state.nonces[sender]; ok {
+			return nonce
+		}
+	}
+	return 0
+}
+
+// included 判断交易是否已被打包
+func (s *chainState) included(id string) bool {
+	for state := s; state != nil; state = state.parent {
+		if state.transactions[id] {
+			return true
+		}
+	}
+	return false
+}
+
+// post 查找已确认的帖子或评论
+func (s *chainState) post(id string) (postRecord, bool) {
+	for state := s; state != nil; state = state.parent {
+		if post, ok := state.posts[id]; ok {
+			return post, true
+		}
+	}
+	return postRecord{}, false
+}
+
+// liked 判断发送者当前是否点赞了帖子
+func (s *chainState) liked(sender, postID string) bool {
+	key := likeKey{Sender: sender, PostID: postID}
+	for state := s; state != nil; state = state.parent {
+		if liked, ok := state.likes[key]; ok {
+			return liked
+		}
+	}
+	return false
+}
+
+// following 判断关注者当前是否关注了被关注者
+func (s *chainState) following(follower, followee string) bool {
+	key := followKey{Follower: follower, Followee: followee}
+	for state := s; state != nil; state = state.parent {
+		if following, ok := state.follows[key]; ok {
+			return following
+		}
+	}
+	return false
+}
+
+// executeBlock 在子状态上按顺序执行区块中的交易，返回尚未合并的子状态
+// 任意一笔不满足状态规则时返回错误，调用方在区块持久化后再 commit
+func (s *chainState) executeBlock(block *Block) (*chainState, error) {
+	child := s.child()
+	for i := range block.Transactions {
+		if err := child.applyTransaction(&block.Transactions[i]); err != nil {
+			return nil, fmt.Errorf("transaction %d (%s): %v", i, block.Transactions[i].ID, err)
+		}
+	}
+	return child, nil
+}
+
+// applyTransaction 检查并执行单笔交易的状态规则：
+// 签名信封交易的序号必须是发送者上一个序号加 1，评论和点赞的目标必须是已确认的帖子或评论，
+// 不能重复点赞或关注，取消点赞和取消关注要求已经点赞或关注
+// 系统交易和旧交易不受这些规则约束，但其中的帖子和评论仍会被索引
+func (s *chainState) applyTransaction(tx *Transaction) error {
+	if tx.Sender != systemSender && tx.Version >= TransactionVersionEnvelope {
+		if expected := s.nonce(tx.Sender) + 1; tx.Nonce != expected {
+			return fmt.Errorf("invalid nonce: expected %d, got %d", expected, tx.Nonce)
+		}
+		if err := validateTarget(tx, s.post); err != nil {
+			return err
+		}
+		if err := validateLikeState(tx, s.liked(tx.Sender, tx.TargetPostID)); err != nil {
+			return err
+		}
+		if err := validateFollowState(tx, s.following(tx.Sender, tx.Receiver)); err != nil {
+			return err
+		}
+		s.nonces[tx.Sender] = tx.Nonce
+	}
+
+	switch tx.kind() {
+	case KindLike:
+		s.likes[likeKey{Sender: tx.Sender, PostID: tx.TargetPostID}] = true
+	case KindUnlike:
+		s.likes[likeKey{Sender: tx.Sender, PostID: tx.TargetPostID}] = false
+	case KindFollow:
+		s.follows[followKey{Follower: tx.Sender, Followee: tx.Receiver}] = true
+	case KindUnfollow:
+		s.follows[followKey{Follower: tx.Sender, Followee: tx.Receiver}] = false
+	}
+
+	switch kind := tx.kind(); kind {
+	case KindPost, KindComment:
+		s.posts[tx.ID] = postRecord{Sender: tx.Sender, Kind: kind}
+	case KindDelete:
+		if post, ok := s.post(tx.TargetPostID); ok {
+			post.Deleted = true
+			s.posts[tx.TargetPostID] = post
+		}
+	}
+	s.transactions[tx.ID] = true
+	return nil
+}
+
+// validateLikeState 点赞要求尚未点赞，取消点赞要求已经点赞
+func validateLikeState(tx *Transaction, liked bool) error {
+	switch tx.kind() {
+	case KindLike:
+		if liked {
+			return fmt.Errorf("post %s is already liked", tx.TargetPostID)
+		}
+	case KindUnlike:
+		if !liked {
+			return fmt.Errorf("post %s is not liked", tx.TargetPostID)
+		}
+	}
+	return nil
+}
+
+// validateFollowState 关注要求尚未关注，取消关注要求已经关注
+func validateFollowState(tx *Transaction, following bool) error {
+	switch tx.kind() {
+	case KindFollow:
+		if following {
+			return fmt.Errorf("%s is already followed", tx.Receiver)
+		}
+	case KindUnfollow:
+		if !following {
+			return fmt.Errorf("%s is not followed", tx.Receiver)
+		}
+	}
+	return nil
+}
+
+// validateTarget 验证交易引用的帖子或评论：
+// 评论、点赞和取消点赞的接收者必须是目标的作者，评论和点赞不能指向已删除的帖子；
+// 编辑和删除只能由原作者签名，且目标未被删除
+func validateTarget(tx *Transaction, lookup postLookup) error {
+	kind := tx.kind()
+	switch kind {
+	case KindComment, KindLike, KindUnlike, KindEdit, KindDelete:
+	default:
+		return nil
+	}
+
+	target, ok := lookup(tx.TargetPostID)
+	if !ok {
+		return fmt.Errorf("unknown target post: %s", tx.TargetPostID)
+	}
+
+	switch kind {
+	case KindEdit, KindDelete:
+		if tx.Sender != target.Sender {
+			return fmt.Errorf("only the author can %s post %s", kind, tx.TargetPostID)
+		}
+	default:
+		if tx.Receiver != target.Sender {
+			return fmt.Errorf("receiver must be the author of the target post %s", tx.TargetPostID)
+		}
+	}
+
+	if target.Deleted && kind != KindUnlike {
+		return fmt.Errorf("target post %s has been deleted", tx.TargetPostID)
+	}
+	return nil
+}
+
+// executable 返回在当前状态上可以按顺序执行的交易，跳过不满足状态规则的交易，当前状态保持不变
+func (s *chainState) executable(transactions []Transaction) []Transaction {
+	child := s.child()
+	result := make([]Transaction, 0, len(transactions))
+	for i := range transactions {
+		if err := child.applyTransaction(&transactions[i]); err != nil {
+			continue
+		}
+		result = append(result, transactions[i])
+	}
+	return result
+}
@@ -0,0 +1,223 @@
+package blockchain
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"twichain/internal/config"
+	"twichain/internal/storage"
+)
+
+// 未配置讨论串查询限制时使用的默认值
+const (
+	defaultThreadMaxDepth    = 5
+	defaultThreadPageSize    = 20
+	defaultThreadMaxPageSize = 100
+	defaultThreadMaxComments = 500
+)
+
+// ThreadPolicy 讨论串查询限制
+type ThreadPolicy struct {
+	MaxDepth    int // 评论树的最大展开深度，也是未指定深度时的默认值
+	PageSize    int // 每个帖子或评论默认展开的回复数
+	MaxPageSize int // 每个帖子或评论最多展开的回复数
+	MaxComments int // 一次请求最多返回的评论总数
+}
+
+// newThreadPolicy 从配置创建讨论串查询限制
+func newThreadPolicy(cfg *config.Config) ThreadPolicy {
+	policy := ThreadPolicy{
+		MaxDepth:    cfg.Threads.MaxDepth,
+		PageSize:    cfg.Threads.PageSize,
+		MaxPageSize: cfg.Threads.MaxPageSize,
+		MaxComments: cfg.Threads.MaxComments,
+	}
+	if policy.MaxDepth <= 0 {
+		policy.MaxDepth = defaultThreadMaxDepth
+	}
+	if policy.MaxPageSize <= 0 {
+		policy.MaxPageSize = defaultThreadMaxPageSize
+	}
+	if policy.PageSize <= 0 {
+		policy.PageSize = defaultThreadPageSize
+	}
+	policy.PageSize = min(policy.PageSize, policy.MaxPageSize)
+	if policy.MaxComments <= 0 {
+		policy.MaxComments = defaultThreadMaxComments
+	}
+	return policy
+}
+
+// ThreadItem 讨论串中的帖子或评论，交易池中尚未上链的条目 Confirmed 为 false 且没有区块索引
+type ThreadItem struct {
+	ID           string        `json:"id"`
+	Sender       string        `json:"sender"`
+	Receiver     string        `json:"receiver"`
+	Kind         Kind          `json:"kind"`
+	TargetPostID string        `json:"target_post_id,omitempty"`
+	Message      string        `json:"message"` // 最后一次编辑后的内容，已删除时为空
+	Timestamp    time.Time     `json:"timestamp"`
+	BlockIndex   int           `json:"block_index,omitempty"`
+	Confirmed    bool          `json:"confirmed"`
+	Edited       bool          `json:"edited"`
+	Deleted      bool          `json:"deleted"`
+	Likes        int           `json:"likes"`       // 已上链的点赞数
+	ReplyCount   int           `json:"reply_count"` // 已上链的直接回复总数，可能多于 Replies 中已上链的条目
+	Replies      []*ThreadItem `json:"replies"`
+}
+
+// Thread 帖子或评论及其评论树，第一层回复按 Offset 分页
+type Thread struct {
+	Post       *ThreadItem `json:"post"`
+	Depth      int         `json:"depth"`
+	Limit      int         `json:"limit"`
+	Offset     int         `json:"offset"`
+	NextOffset int         `json:"next_offset,omitempty"` // 第一层还有更多回复时的偏移量
+	Truncated  bool        `json:"truncated"`             // 达到评论总数上限，更深的回复没有展开
+}
+
+// GetThread 返回帖子或评论及其评论树：已上链的部分从存储的 target_post_id 索引逐层读取，
+// 每层每个条目最多展开 limit 条回复，第一层从 offset 开始；交易池中的待打包评论附在各自父条目的回复末尾
+// depth 和 limit 不大于 0 时使用配置的默认值
+func (bc *Blockchain) GetThread(id string, depth, limit, offset int) (*Thread, error) {
+	policy := bc.threads
+	if depth <= 0 || depth > policy.MaxDepth {
+		depth = policy.MaxDepth
+	}
+	if limit <= 0 {
+		limit = policy.PageSize
+	}
+	limit = min(limit, policy.MaxPageSize)
+	if offset < 0 {
+		return nil, fmt.Errorf("offset must not be negative")
+	}
+
+	bc.mu.RLock()
+	defer bc.mu.RUnlock()
+
+	pending := bc.pendingReplies()
+	root, err := bc.threadRoot(id)
+	if err != nil {
+		return nil, err
+	}
+
+	thread := &Thread{Post: root, Depth: depth, Limit: limit, Offset: offset}
+	if root.ReplyCount > offset+limit {
+		thread.NextOffset = offset + limit
+	}
+
+	remaining := policy.MaxComments
+	level := []*ThreadItem{root}
+	for d := 1; d <= depth && len(level) > 0; d++ {
+		levelOffset := 0
+		if d == 1 {
+			levelOffset = offset
+		}
+
+		parents := make(map[string]*ThreadItem, len(level))
+		var parentIDs []string
+		for _, item := range level {
+			parents[item.ID] = item
+			if item.Confirmed && item.ReplyCount > levelOffset {
+				parentIDs = append(parentIDs, item.ID)
+			}
+		}
+
+		replies, err := bc.storage.GetReplies(parentIDs, limit, levelOffset)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read replies: %v", err)
+		}
+
+		var next []*ThreadItem
+		attach := func(parent, reply *ThreadItem) bool {
+			if remaining == 0 {
+				thread.Truncated = true
+				return false
+			}
+			remaining--
+			parent.Replies = append(parent.Replies, reply)
+			next = append(next, reply)
+			return true
+		}
+		for i := range replies {
+			if !attach(parents[replies[i].TargetPostID], confirmedThreadItem(&replies[i])) {
+				break
+			}
+		}
+		// 待打包的第一层回复只附在第一页
+		for _, item := range level {
+			if d == 1 && offset > 0 {
+				break
+			}
+			for _, reply := range pending[item.ID] {
+				if !attach(item, reply) {
+					break
+				}
+			}
+		}
+		level = next
+	}
+	return thread, nil
+}
+
+// threadRoot 查找讨论串的根条目，先查已上链的帖子和评论，再查交易池，调用方需持有锁
+func (bc *Blockchain) threadRoot(id string) (*ThreadItem, error) {
+	item, err := bc.storage.GetThreadItem(id)
+	if err == nil {
+		return confirmedThreadItem(item), nil
+	}
+	if err != sql.ErrNoRows {
+		return nil, fmt.Errorf("failed to read post: %v", err)
+	}
+
+	tx, ok := bc.pool.Get(id)
+	if !ok || (tx.kind() != KindPost && tx.kind() != KindComment) {
+		return nil, fmt.Errorf("post not found: %s", id)
+	}
+	return pendingThreadItem(&tx), nil
+}
+
+// pendingReplies 按父条目分组交易池中的待打包评论，组内按打包顺序排列，调用方需持有锁
+func (bc *Blockchain) pendingReplies() map[string][]*ThreadItem {
+	replies := make(map[string][]*ThreadItem)
+	for _, tx := range bc.pool.Select(0) {
+		if tx.kind() != KindComment {
+			continue
+		}
+		replies[tx.TargetPostID] = append(replies[tx.TargetPostID], pendingThreadItem(&tx))
+	}
+	return replies
+}
+
+func confirmedThreadItem(item *storage.ThreadItemData) *ThreadItem {
+	return &ThreadItem{
+		ID:           item.ID,
+		Sender:       item.Sender,
+		Receiver:     item.Receiver,
+		Kind:         Kind(item.Kind),
+		TargetPostID: item.TargetPostID,
+		Message:      item.Message,
+		Timestamp:    item.Timestamp,
+		BlockIndex:   item.BlockIndex,
+		Confirmed:    true,
+		Edited:       item.Edited,
+		Deleted:      item.Deleted,
+		Likes:        item.Likes,
+		ReplyCount:   item.Replies,
+		Replies:      []*ThreadItem{},
+	}
+}
+
+func pendingThreadItem(tx *Transaction) *ThreadItem {
+	return &ThreadItem{
+		ID:           tx.ID,
+		Sender:       tx.Sender,
+		Receiver:     tx.Receiver,
+		Kind:         tx.kind(),
+		TargetPostID: tx.TargetPostID,
+		Message:      tx.Message,
+		Timestamp:    tx.Timestamp,
+		Replies:      []*ThreadItem{},
+	}
+}
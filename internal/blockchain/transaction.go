@@ -1,17 +1,34 @@
 package blockchain
 
-import "time"
+import (
+	"time"
 
-// Transaction 代表区块链中的一个交互行为(发帖/评论/点赞)
+	"twichain/internal/crypto"
+)
+
+// 交易签名格式版本
+const (
+	// TransactionVersionLegacy 旧交易：只签名消息内容，点赞只签名目标帖子 ID
+	TransactionVersionLegacy = 0
+	// TransactionVersionEnvelope 签名覆盖完整交易信封，见 SignBytes
+	TransactionVersionEnvelope = 1
+	// TransactionVersionCurrent 新交易必须使用的版本
+	TransactionVersionCurrent = TransactionVersionEnvelope
+)
+
+// Transaction 代表区块链中的一个交互行为(发帖/评论/点赞/关注等，见 Kind)
 type Transaction struct {
-	ID           string    `json:"id"`             // 交易ID
-	Sender       string    `json:"sender"`         // 发送者地址(256位十六进制)
-	Receiver     string    `json:"receiver"`       // 接收者地址(256位十六进制)
-	Signature    string    `json:"signature"`      // EdDSA签名(r,s)
-	IsLike       bool      `json:"is_like"`        // 是否是点赞
-	Timestamp    time.Time `json:"timestamp"`      // 时间戳
-	Message      string    `json:"message"`        // 原始消息内容
-	TargetPostID string    `json:"target_post_id"` // 目标帖子ID（点赞时必填）
+	ID           string    `json:"id"`                // 交易ID
+	Sender       string    `json:"sender"`            // 发送者地址(256位十六进制)
+	Receiver     string    `json:"receiver"`          // 接收者地址(256位十六进制)
+	Signature    string    `json:"signature"`         // EdDSA签名(r,s)
+	IsLike       bool      `json:"is_like"`           // 是否是点赞
+	Timestamp    time.Time `json:"timestamp"`         // 时间戳
+	Message      string    `json:"message"`           // 原始消息内容
+	TargetPostID string    `json:"target_post_id"`    // 目标帖子ID（点赞时必填）
+	Version      int       `json:"version,omitempty"` // 签名格式版本，旧交易为 0
+	Nonce        uint64    `json:"nonce,omitempty"`   // 发送者序号，包含在签名中
+	Kind         Kind      `json:"kind,omitempty"`    // 交易类型，旧交易为空，由 IsLike 和 TargetPostID 推导
 }
 
 // NewTransaction 创建新交易
@@ -27,3 +44,56 @@ func NewTransaction(id, sender, receiver, signature string, isLike bool, message
 		TargetPostID: targetPostID,
 	}
 }
+
+// kind 返回交易类型，未显式指定时推导为点赞、评论（有目标帖子）或发帖
+func (tx *Transaction) kind() Kind {
+	switch {
+	case tx.Kind != "":
+		return tx.Kind
+	case tx.IsLike:
+		return KindLike
+	case tx.TargetPostID != "":
+		return KindComment
+	default:
+		return KindPost
+	}
+}
+
+// ComputeID 计算内容寻址的交易 ID：已签名交易体规范编码的哈希
+// 引用 TargetPostID 的点赞和评论因此指向不可篡改的内容
+func (tx *Transaction) ComputeID() string {
+	return crypto.Hash(tx.bodyBytes())
+}
+
+// Hash 计算交易规范编码的哈希，用作 Merkle 树的叶子
+func (tx *Transaction) Hash() string {
+	return crypto.Hash(tx.CanonicalBytes())
+}
+
+// TxID 实现 mempool.Tx
+func (tx Transaction) TxID() string {
+	return tx.ID
+}
+
+// TxSender 实现 mempool.Tx
+func (tx Transaction) TxSender() string {
+	return tx.Sender
+}
+
+// TxSignature 实现 mempool.Tx
+func (tx Transaction) TxSignature() string {
+	return tx.Signature
+}
+
+// TxNonce 实现 mempool.Tx，旧交易不带序号
+func (tx Transaction) TxNonce() uint64 {
+	if tx.Version < TransactionVersionEnvelope {
+		return 0
+	}
+	return tx.Nonce
+}
+
+// TxTimestamp 实现 mempool.Tx
+func (tx Transaction) TxTimestamp() time.Time {
+	return tx.Timestamp
+}
@@ -0,0 +1,136 @@
+package blockchain
+
+import (
+	"fmt"
+	"log"
+)
+
+// removeIncludedTransactions 从交易池中移除已被这些区块打包的交易，
+// 以及序号已被打包交易占用的同一发送者的其他交易
+func (bc *Blockchain) removeIncludedTransactions(blocks []*Block) {
+	for _, block := range blocks {
+		for _, tx := range block.Transactions {
+			bc.pool.Remove(tx.ID)
+			if nonce := tx.TxNonce(); nonce > 0 {
+				bc.pool.RemoveThroughNonce(tx.Sender, nonce)
+			}
+		}
+	}
+}
+
+// revalidatePool 在链顶变化后用新的链上状态重新检查交易池，移除已经无法执行的交易，调用方需持有锁
+// 例如评论的目标被其他节点出的区块删除，或者同一序号已被另一笔交易占用；
+// 这些交易留在池中会一直占用发送者的序号，使其后续交易因序号重复或不连续被拒绝
+// 交易之间可能互相依赖（例如评论交易池中的帖子），因此反复执行直到没有新的交易可以执行
+func (bc *Blockchain) revalidatePool() {
+	state := bc.state.child()
+	pending := bc.pool.Select(0)
+	for progress := true; progress; {
+		progress = false
+		remaining := pending[:0]
+		for i := range pending {
+			if err := state.applyTransaction(&pending[i]); err != nil {
+				remaining = append(remaining, pending[i])
+				continue
+			}
+			progress = true
+		}
+		pending = remaining
+	}
+
+	for i := range pending {
+		err := state.applyTransaction(&pending[i])
+		log.Printf("Dropped pending transaction %s: %v", pending[i].ID, err)
+		bc.pool.Remove(pending[i].ID)
+	}
+}
+
+// lookupPost 在已确认的帖子和交易池中查找帖子或评论，调用方需持有锁
+func (bc *Blockchain) lookupPost(id string) (postRecord, bool) {
+	if post, ok := bc.state.post(id); ok {
+		return post, true
+	}
+	tx, ok := bc.pool.Get(id)
+	if !ok {
+		return postRecord{}, false
+	}
+	if kind := tx.kind(); kind == KindPost || kind == KindComment {
+		return postRecord{Sender: tx.Sender, Kind: kind}, true
+	}
+	return postRecord{}, false
+}
+
+// pendingLiked 判断在已确认状态上依次执行发送者的待打包交易后，发送者是否点赞了帖子，调用方需持有锁
+func (bc *Blockchain) pendingLiked(sender, postID string) bool {
+	liked := bc.state.liked(sender, postID)
+	for _, tx := range bc.pool.BySender(sender) {
+		if tx.TargetPostID != postID {
+			continue
+		}
+		switch tx.kind() {
+		case KindLike:
+			liked = true
+		case KindUnlike:
+			liked = false
+		}
+	}
+	return liked
+}
+
+// pendingFollowing 判断在已确认状态上依次执行关注者的待打包交易后，关注者是否关注了被关注者，调用方需持有锁
+func (bc *Blockchain) pendingFollowing(follower, followee string) bool {
+	following := bc.state.following(follower, followee)
+	for _, tx := range bc.pool.BySender(follower) {
+		if tx.Receiver != followee {
+			continue
+		}
+		switch tx.kind() {
+		case KindFollow:
+			following = true
+		case KindUnfollow:
+			following = false
+		}
+	}
+	return following
+}
+
+// checkPending 在已确认状态和交易池上检查交易能否加入交易池，调用方需持有锁：
+// 交易尚未被打包，序号紧接已确认和待打包的序号，目标帖子、点赞和关注状态在待打包交易执行后仍然有效
+func (bc *Blockchain) checkPending(tx *Transaction) error {
+	if bc.state.included(tx.ID) {
+		return fmt.Errorf("transaction already included in chain: %s", tx.ID)
+	}
+	if err := bc.pool.CheckNonce(tx.Sender, tx.Nonce, bc.state.nonce(tx.Sender)); err != nil {
+		return err
+	}
+	if err := validateTarget(tx, bc.lookupPost); err != nil {
+		return err
+	}
+	if err := validateLikeState(tx, bc.pendingLiked(tx.Sender, tx.TargetPostID)); err != nil {
+		return err
+	}
+	return validateFollowState(tx, bc.pendingFollowing(tx.Sender, tx.Receiver))
+}
+
+// restoreOrphanedTransactions 将被分叉切换丢弃的区块中的交易放回交易池，调用方需持有锁，且已切换到新链的状态
+// 每笔交易按区块顺序经过与新交易相同的交易池检查，已被新链打包或在新链上无法执行的交易直接丢弃
+// 系统交易和非内容寻址 ID 的旧交易不会放回
+func (bc *Blockchain) restoreOrphanedTransactions(orphaned []*Block) {
+	for _, block := range orphaned {
+		for _, tx := range block.Transactions {
+			if tx.Sender == systemSender || tx.ID != tx.ComputeID() {
+				continue
+			}
+			if _, ok := bc.pool.Get(tx.ID); ok {
+				continue
+			}
+			if err := bc.checkPending(&tx); err != nil {
+				log.Printf("Dropped orphaned transaction %s: %v", tx.ID, err)
+				continue
+			}
+			if err := bc.pool.Add(tx); err != nil {
+				log.Printf("Dropped orphaned transaction %s: %v", tx.ID, err)
+			}
+		}
+	}
+}
@@ -0,0 +1,116 @@
+package blockchain
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAddBlockEvictsStuckTransactions(t *testing.T) {
+	bc := newTestBlockchain(t)
+	alice, bob := newTestAccount(1), newTestAccount(2)
+
+	post := alice.post(t, 1, "hello")
+	if err := bc.AddBlock(bc.mineTestBlock(t, bc.Chain, post)); err != nil {
+		t.Fatalf("AddBlock(post): %v", err)
+	}
+
+	// bob 的评论进入交易池后，目标帖子被其他节点出的区块删除
+	comment := bob.transaction(t, KindComment, 1, alice.public, "nice", post.ID)
+	if _, err := bc.addPendingTransaction(comment); err != nil {
+		t.Fatalf("addPendingTransaction(comment): %v", err)
+	}
+	deletion := alice.transaction(t, KindDelete, 2, alice.public, "", post.ID)
+	if err := bc.AddBlock(bc.mineTestBlock(t, bc.Chain, deletion)); err != nil {
+		t.Fatalf("AddBlock(delete): %v", err)
+	}
+
+	if bc.pool.Has(comment.ID) {
+		t.Errorf("comment on a deleted post is still pending")
+	}
+	// 序号 1 已经释放，bob 可以用它提交新的交易
+	if _, err := bc.addPendingTransaction(bob.post(t, 1, "retry")); err != nil {
+		t.Errorf("addPendingTransaction with the freed nonce = %v, want nil", err)
+	}
+}
+
+func TestRevalidatePoolKeepsDependentTransactions(t *testing.T) {
+	bc := newTestBlockchain(t)
+	alice, bob := newTestAccount(1), newTestAccount(2)
+
+	// 评论的时间戳早于交易池中的目标帖子，需要在帖子之后才能执行
+	// 交易池不验证签名，直接修改时间戳即可
+	post := alice.post(t, 1, "pending")
+	comment := bob.transaction(t, KindComment, 1, alice.public, "early", post.ID)
+	comment.Timestamp = post.Timestamp.Add(-time.Second)
+	for _, tx := range []Transaction{post, comment} {
+		if err := bc.pool.Add(tx); err != nil {
+			t.Fatalf("pool.Add(%s): %v", tx.ID, err)
+		}
+	}
+
+	bc.revalidatePool()
+	if !bc.pool.Has(post.ID) || !bc.pool.Has(comment.ID) {
+		t.Errorf("revalidatePool dropped executable transactions")
+	}
+}
+
+func TestReplaceChainRestoresOnlyExecutableOrphans(t *testing.T) {
+	bc := newTestBlockchain(t)
+	alice, bob, carol, dave := newTestAccount(1), newTestAccount(2), newTestAccount(3), newTestAccount(4)
+
+	post := alice.post(t, 1, "hello")
+	if err := bc.AddBlock(bc.mineTestBlock(t, bc.Chain, post)); err != nil {
+		t.Fatalf("AddBlock(post): %v", err)
+	}
+	common := bc.GetChain()
+
+	// 本地分支：bob 评论帖子，carol 和 dave 各发一篇帖子
+	comment := bob.transaction(t, KindComment, 1, alice.public, "nice", post.ID)
+	carolPost, davePost := carol.post(t, 1, "carol"), dave.post(t, 1, "dave")
+	if err := bc.AddBlock(bc.mineTestBlock(t, bc.Chain, comment, carolPost, davePost)); err != nil {
+		t.Fatalf("AddBlock(local branch): %v", err)
+	}
+
+	// 更重的分支：alice 删除了帖子，carol 的帖子也被打包
+	newChain := append([]*Block(nil), common...)
+	newChain = append(newChain, bc.mineTestBlock(t, newChain, alice.transaction(t, KindDelete, 2, alice.public, "", post.ID)))
+	newChain = append(newChain, bc.mineTestBlock(t, newChain, carolPost))
+	state, err := bc.replayChain(newChain)
+	if err != nil {
+		t.Fatalf("replayChain: %v", err)
+	}
+	if replaced, err := bc.replaceChain(newChain, state); err != nil || !replaced {
+		t.Fatalf("replaceChain = %v, %v, want true, nil", replaced, err)
+	}
+
+	if bc.pool.Has(comment.ID) {
+		t.Errorf("orphaned comment on a deleted post was restored")
+	}
+	if bc.pool.Has(carolPost.ID) {
+		t.Errorf("orphaned transaction included in the new chain was restored")
+	}
+	if !bc.pool.Has(davePost.ID) {
+		t.Errorf("executable orphaned transaction was not restored")
+	}
+}
+
+func TestAddPendingRejectsIncludedTransaction(t *testing.T) {
+	bc := newTestBlockchain(t)
+	alice := newTestAccount(1)
+
+	post := alice.post(t, 1, "hello")
+	if err := bc.AddBlock(bc.mineTestBlock(t, bc.Chain, post)); err != nil {
+		t.Fatalf("AddBlock: %v", err)
+	}
+
+	_, err := bc.addPendingTransaction(post)
+	if err == nil || !strings.Contains(err.Error(), "already included") {
+		t.Errorf("addPendingTransaction(included) = %v, want already included", err)
+	}
+	// 试执行交易池的子状态不会把交易记为已打包
+	bc.state.executable([]Transaction{alice.post(t, 2, "pending")})
+	if bc.state.included(alice.post(t, 2, "pending").ID) {
+		t.Errorf("executable marked a pending transaction as included")
+	}
+}
@@ -0,0 +1,270 @@
+package blockchain
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"twichain/internal/consensus"
+	"twichain/internal/crypto"
+)
+
+// 创世块的固定字段
+const (
+	genesisProof    int64 = 100
+	genesisPrevHash       = "1"
+	systemSender          = "SYSTEM" // 创世交易的发送者，不需要签名
+)
+
+// 交易时间戳允许领先本地时钟的最大偏差
+const maxTransactionClockSkew = 5 * time.Minute
+
+const (
+	// maxBlockClockSkew 区块时间戳允许领先本地时钟的最大偏差
+	maxBlockClockSkew = 2 * time.Minute
+	// medianTimeBlocks 区块时间戳必须晚于最近多少个区块时间戳的中位数
+	medianTimeBlocks = 11
+)
+
+// ValidateChain 从创世块开始逐块验证整条链：索引连续性、哈希链接、共识规则、交易签名和状态规则
+// 同步、重启恢复和分叉处理都通过它来判断链是否可信
+func (bc *Blockchain) ValidateChain(chain []*Block) error {
+	_, err := bc.replayChain(chain)
+	return err
+}
+
+// replayChain 验证整条链并返回重放后的链上状态
+func (bc *Blockchain) replayChain(chain []*Block) (*chainState, error) {
+	if len(chain) == 0 {
+		return nil, fmt.Errorf("empty chain")
+	}
+
+	if err := validateGenesis(chain[0]); err != nil {
+		return nil, fmt.Errorf("block %d: %v", chain[0].Index, err)
+	}
+
+	state := newChainState()
+	for i, block := range chain {
+		if block == nil {
+			return nil, fmt.Errorf("block at position %d is missing", i)
+		}
+		if i > 0 {
+			if err := bc.validateBlock(block, chain[:i]); err != nil {
+				return nil, fmt.Errorf("block %d: %v", block.Index, err)
+			}
+		}
+		next, err := state.executeBlock(block)
+		if err != nil {
+			return nil, fmt.Errorf("block %d: %v", block.Index, err)
+		}
+		next.commit()
+	}
+
+	return state, nil
+}
+
+// validateGenesis 验证创世块的固定字段
+func validateGenesis(block *Block) error {
+	if block == nil {
+		return fmt.Errorf("genesis block is missing")
+	}
+	if err := validateVersion(block); err != nil {
+		return err
+	}
+	if block.Index != 1 {
+		return fmt.Errorf("invalid genesis index: expected 1, got %d", block.Index)
+	}
+	if block.PrevHash != genesisPrevHash {
+		return fmt.Errorf("invalid genesis previous hash: %q", block.PrevHash)
+	}
+	if block.Proof != genesisProof {
+		return fmt.Errorf("invalid genesis proof: %d", block.Proof)
+	}
+	if err := validateTransactionIDs(block); err != nil {
+		return err
+	}
+	return validateMerkleRoot(block)
+}
+
+// validateVersion 拒绝未知的区块编码版本
+func validateVersion(block *Block) error {
+	if block.Version < consensus.VersionLegacy || block.Version > consensus.VersionCurrent {
+		return fmt.Errorf("unsupported block version %d", block.Version)
+	}
+	return nil
+}
+
+// validateMerkleRoot 验证区块头中的 Merkle 根与交易一致，旧区块没有 Merkle 根时跳过
+func validateMerkleRoot(block *Block) error {
+	if block.MerkleRoot == "" {
+		if block.Version >= consensus.VersionCanonical {
+			return fmt.Errorf("missing merkle root")
+		}
+		return nil
+	}
+	if root := computeMerkleRoot(block.Transactions); block.MerkleRoot != root {
+		return fmt.Errorf("invalid merkle root: expected %s, got %s", root, block.MerkleRoot)
+	}
+	return nil
+}
+
+// validateTransactionIDs 验证区块中的交易 ID 均为内容寻址的哈希，旧版本区块的交易 ID 不做要求
+func validateTransactionIDs(block *Block) error {
+	if block.Version < consensus.VersionContentID {
+		return nil
+	}
+	for i := range block.Transactions {
+		if err := validateTransactionID(&block.Transactions[i]); err != nil {
+			return fmt.Errorf("transaction %d: %v", i, err)
+		}
+	}
+	return nil
+}
+
+// validateBlock 验证区块能否接在 chain 之后（索引、哈希链接、共识规则、交易地址和签名）
+func (bc *Blockchain) validateBlock(block *Block, chain []*Block) error {
+	lastBlock := chain[len(chain)-1]
+	if err := validateVersion(block); err != nil {
+		return err
+	}
+	// 不允许在新版本区块之后出现旧版本区块
+	if block.Version < lastBlock.Version {
+		return fmt.Errorf("block version %d is older than parent version %d", block.Version, lastBlock.Version)
+	}
+
+	if block.Index != lastBlock.Index+1 {
+		return fmt.Errorf("invalid block index: expected %d, got %d", lastBlock.Index+1, block.Index)
+	}
+
+	if lastHash := lastBlock.Hash(); block.PrevHash != lastHash {
+		return fmt.Errorf("invalid previous hash: expected %s, got %s", lastHash, block.PrevHash)
+	}
+
+	if err := validateTimestamp(block, chain); err != nil {
+		return err
+	}
+
+	if err := validateMerkleRoot(block); err != nil {
+		return err
+	}
+
+	if err := validateTransactionIDs(block); err != nil {
+		return err
+	}
+
+	// 由共识引擎验证难度、工作量证明或出块者签名
+	if err := bc.engine.VerifyHeader(headerReader(chain), block.Header()); err != nil {
+		return err
+	}
+
+	// 验证所有交易的地址格式、类型载荷和签名
+	for i := range block.Transactions {
+		if !crypto.ValidateAddress(block.Transactions[i].Sender) || !crypto.ValidateAddress(block.Transactions[i].Receiver) {
+			return fmt.Errorf("transaction %d (%s): invalid address format - must be 256-bit hex string",
+				i, block.Transactions[i].ID)
+		}
+		if err := validateKind(&block.Transactions[i]); err != nil {
+			return fmt.Errorf("transaction %d (%s): %v", i, block.Transactions[i].ID, err)
+		}
+		if err := bc.verifyTransactionSignature(block.Version, &block.Transactions[i]); err != nil {
+			return fmt.Errorf("transaction %d (%s): %v", i, block.Transactions[i].ID, err)
+		}
+	}
+
+	return nil
+}
+
+// validateTimestamp 验证区块时间戳晚于最近 medianTimeBlocks 个区块的中位时间，且不超前本地时钟太多
+// 难度调整依赖区块时间戳，不加约束时出块者可以通过伪造时间戳操纵难度
+// 与中位时间而不是父区块比较，节点之间少量的时钟偏差不会导致合法区块被拒绝
+func validateTimestamp(block *Block, chain []*Block) error {
+	if median := medianTime(chain); !block.Timestamp.After(median) {
+		return fmt.Errorf("block timestamp %s is not after the median time %s of recent blocks",
+			block.Timestamp.Format(time.RFC3339Nano), median.Format(time.RFC3339Nano))
+	}
+	if block.Timestamp.After(time.Now().Add(maxBlockClockSkew)) {
+		return fmt.Errorf("block timestamp is too far in the future")
+	}
+	return nil
+}
+
+// medianTime 返回链上最近 medianTimeBlocks 个区块时间戳的中位数，空链返回零值
+func medianTime(chain []*Block) time.Time {
+	if len(chain) == 0 {
+		return time.Time{}
+	}
+	recent := chain[max(0, len(chain)-medianTimeBlocks):]
+	timestamps := make([]time.Time, len(recent))
+	for i, block := range recent {
+		timestamps[i] = block.Timestamp
+	}
+	sort.Slice(timestamps, func(i, j int) bool { return timestamps[i].Before(timestamps[j]) })
+	return timestamps[len(timestamps)/2]
+}
+
+// nextBlockTime 返回新区块的时间戳：本地时钟落后于链上中位时间时，取中位时间之后的最小时间，保证区块有效
+func nextBlockTime(chain []*Block) time.Time {
+	now := time.Now()
+	if median := medianTime(chain); !now.After(median) {
+		return median.Add(time.Nanosecond)
+	}
+	return now
+}
+
+// validateTransaction 验证待打包交易：签名版本、类型载荷、地址格式、时间戳和签名
+// 本地提交和从其他节点收到的交易都必须通过它才能进入交易池
+func (bc *Blockchain) validateTransaction(tx *Transaction) error {
+	if tx.Version != TransactionVersionCurrent {
+		return fmt.Errorf("unsupported transaction version %d: new transactions must use version %d",
+			tx.Version, TransactionVersionCurrent)
+	}
+	if err := validateTransactionID(tx); err != nil {
+		return err
+	}
+	if !crypto.ValidateAddress(tx.Sender) || !crypto.ValidateAddress(tx.Receiver) {
+		return fmt.Errorf("invalid address format - must be 256-bit hex string")
+	}
+	if err := validateKind(tx); err != nil {
+		return err
+	}
+	if tx.Timestamp.IsZero() {
+		return fmt.Errorf("missing transaction timestamp")
+	}
+	if tx.Nonce == 0 {
+		return fmt.Errorf("missing transaction nonce")
+	}
+	if tx.Timestamp.After(time.Now().Add(maxTransactionClockSkew)) {
+		return fmt.Errorf("transaction timestamp is too far in the future")
+	}
+	return bc.verifyTransactionSignature(consensus.VersionCurrent, tx)
+}
+
+// validateTransactionID 重新计算并核对内容寻址的交易 ID
+func validateTransactionID(tx *Transaction) error {
+	if tx.ID == "" {
+		return fmt.Errorf("missing transaction id")
+	}
+	if id := tx.ComputeID(); tx.ID != id {
+		return fmt.Errorf("invalid transaction id: expected %s, got %s", id, tx.ID)
+	}
+	return nil
+}
+
+// verifyTransactionSignature 按交易版本验证签名，旧交易只允许出现在 VersionEnvelope 之前的区块中
+func (bc *Blockchain) verifyTransactionSignature(blockVersion int, tx *Transaction) error {
+	if tx.Version < TransactionVersionLegacy || tx.Version > TransactionVersionCurrent {
+		return fmt.Errorf("unsupported transaction version %d", tx.Version)
+	}
+	if tx.Version < TransactionVersionEnvelope && blockVersion >= consensus.VersionEnvelope {
+		return fmt.Errorf("legacy transaction signature not allowed in block version %d", blockVersion)
+	}
+
+	valid, err := crypto.Verify(tx.Sender, tx.SignBytes(bc.chainID), tx.Signature)
+	if err != nil {
+		return fmt.Errorf("invalid transaction signature: %v", err)
+	}
+	if !valid {
+		return fmt.Errorf("invalid transaction signature")
+	}
+	return nil
+}
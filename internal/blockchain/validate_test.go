@@ -0,0 +1,253 @@
+package blockchain
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"encoding/hex"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"twichain/internal/consensus"
+	"twichain/internal/crypto"
+	"twichain/internal/mempool"
+	"twichain/internal/storage"
+)
+
+const testChainID = "test"
+
+// testStart 测试链创世块的时间，之后每个区块间隔一分钟，保证时间戳不超前本地时钟
+var testStart = time.Now().Add(-24 * time.Hour).Truncate(time.Second)
+
+// testAccount 测试用的 ed25519 账户
+type testAccount struct {
+	public  string
+	private string
+}
+
+func newTestAccount(seed byte) testAccount {
+	key := ed25519.NewKeyFromSeed(bytes.Repeat([]byte{seed}, ed25519.SeedSize))
+	return testAccount{
+		public:  hex.EncodeToString(key.Public().(ed25519.PublicKey)),
+		private: hex.EncodeToString(key),
+	}
+}
+
+// transaction 创建该账户签名的交易，ID 为内容寻址的哈希
+func (a testAccount) transaction(t *testing.T, kind Kind, nonce uint64, receiver, message, target string) Transaction {
+	t.Helper()
+	tx := Transaction{
+		Sender:       a.public,
+		Receiver:     receiver,
+		IsLike:       kind == KindLike,
+		Timestamp:    testStart.Add(time.Duration(nonce) * time.Second),
+		Message:      message,
+		TargetPostID: target,
+		Version:      TransactionVersionCurrent,
+		Nonce:        nonce,
+		Kind:         kind,
+	}
+	signature, err := crypto.Sign(a.private, tx.SignBytes(testChainID))
+	if err != nil {
+		t.Fatalf("Sign: %v", err)
+	}
+	tx.Signature = signature
+	tx.ID = tx.ComputeID()
+	return tx
+}
+
+// post 创建该账户发往自己主页的帖子
+func (a testAccount) post(t *testing.T, nonce uint64, message string) Transaction {
+	t.Helper()
+	return a.transaction(t, KindPost, nonce, a.public, message, "")
+}
+
+// newTestBlockchain 创建使用临时数据库的区块链，使用难度 1 的工作量证明，只包含创世块
+func newTestBlockchain(t *testing.T) *Blockchain {
+	t.Helper()
+	store, err := storage.NewDatabase(filepath.Join(t.TempDir(), "blockchain.db"))
+	if err != nil {
+		t.Fatalf("NewDatabase: %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+
+	bc := &Blockchain{
+		storage:       store,
+		pool:          mempool.New[Transaction](mempool.Config{}),
+		Nodes:         make(map[string]bool),
+		engine:        consensus.NewProofOfWork(1, 0, 0, 1),
+		seen:          newSeenCache(seenTransactionTTL, seenTransactionLimit),
+		chainID:       testChainID,
+		announcements: newAnnounceQueue(),
+	}
+	bc.tipCtx, bc.tipCancel = context.WithCancel(context.Background())
+	bc.Chain = []*Block{testGenesis()}
+	state, err := bc.replayChain(bc.Chain)
+	if err != nil {
+		t.Fatalf("replayChain(genesis): %v", err)
+	}
+	bc.state = state
+	if err := store.SaveBlock(toBlockData(bc.Chain[0])); err != nil {
+		t.Fatalf("SaveBlock(genesis): %v", err)
+	}
+	return bc
+}
+
+func testGenesis() *Block {
+	tx := Transaction{
+		Sender:    systemSender,
+		Receiver:  defaultMainSpace,
+		Signature: "GENESIS",
+		Timestamp: testStart,
+		Message:   "Genesis",
+		Version:   TransactionVersionCurrent,
+		Kind:      KindPost,
+	}
+	tx.ID = tx.ComputeID()
+	transactions := []Transaction{tx}
+	return &Block{
+		Index:        1,
+		Timestamp:    testStart,
+		Transactions: transactions,
+		Proof:        genesisProof,
+		PrevHash:     genesisPrevHash,
+		MerkleRoot:   computeMerkleRoot(transactions),
+		Difficulty:   1,
+		Version:      consensus.VersionCurrent,
+	}
+}
+
+// mineTestBlock 在 chain 之后创建并封装包含 transactions 的区块，时间戳比父区块晚一分钟
+func (bc *Blockchain) mineTestBlock(t *testing.T, chain []*Block, transactions ...Transaction) *Block {
+	t.Helper()
+	parent := chain[len(chain)-1]
+	block := &Block{
+		Index:        parent.Index + 1,
+		Timestamp:    parent.Timestamp.Add(time.Minute),
+		Transactions: transactions,
+		PrevHash:     parent.Hash(),
+		MerkleRoot:   computeMerkleRoot(transactions),
+		Version:      consensus.VersionCurrent,
+	}
+	bc.sealTestBlock(t, chain, block)
+	return block
+}
+
+// sealTestBlock 由共识引擎重新准备并封装区块头
+func (bc *Blockchain) sealTestBlock(t *testing.T, chain []*Block, block *Block) {
+	t.Helper()
+	header := block.Header()
+	if err := bc.engine.Prepare(headerReader(chain), header); err != nil {
+		t.Fatalf("Prepare: %v", err)
+	}
+	if err := bc.engine.Seal(context.Background(), headerReader(chain), header); err != nil {
+		t.Fatalf("Seal: %v", err)
+	}
+	block.setHeader(header)
+}
+
+func TestValidateChainRejectsMalformedAddress(t *testing.T) {
+	bc := newTestBlockchain(t)
+	alice := newTestAccount(1)
+
+	tests := []struct {
+		name string
+		edit func(tx *Transaction)
+	}{
+		{"short sender", func(tx *Transaction) { tx.Sender = "ab" }},
+		{"short receiver", func(tx *Transaction) { tx.Receiver = "ab" }},
+		{"non-hex sender", func(tx *Transaction) { tx.Sender = strings.Repeat("z", 64) }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tx := alice.post(t, 1, "hello")
+			tt.edit(&tx)
+			tx.ID = tx.ComputeID()
+			chain := append(bc.GetChain(), bc.mineTestBlock(t, bc.Chain, tx))
+
+			err := bc.ValidateChain(chain)
+			if err == nil || !strings.Contains(err.Error(), "invalid address format") {
+				t.Errorf("ValidateChain = %v, want invalid address format", err)
+			}
+		})
+	}
+}
+
+func TestValidateChainRejectsInvalidBlocks(t *testing.T) {
+	bc := newTestBlockchain(t)
+	alice, mallory := newTestAccount(1), newTestAccount(2)
+
+	if err := bc.AddBlock(bc.mineTestBlock(t, bc.Chain, alice.post(t, 1, "first"))); err != nil {
+		t.Fatalf("AddBlock: %v", err)
+	}
+	chain := bc.GetChain()
+
+	tests := []struct {
+		name  string
+		block func(t *testing.T) *Block
+		want  string
+	}{
+		{"previous hash", func(t *testing.T) *Block {
+			block := bc.mineTestBlock(t, chain, alice.post(t, 2, "second"))
+			block.PrevHash = chain[0].Hash()
+			return block
+		}, "invalid previous hash"},
+		{"proof of work", func(t *testing.T) *Block {
+			block := bc.mineTestBlock(t, chain, alice.post(t, 2, "second"))
+			// 难度 1 时约 1/16 的 proof 有效，逐个尝试直到找到无效的 proof
+			for block.Proof++; bc.engine.VerifyHeader(headerReader(chain), block.Header()) == nil; block.Proof++ {
+			}
+			return block
+		}, "invalid proof of work"},
+		{"signature", func(t *testing.T) *Block {
+			tx := alice.post(t, 2, "second")
+			tx.Signature = mallory.post(t, 2, "second").Signature
+			tx.ID = tx.ComputeID()
+			return bc.mineTestBlock(t, chain, tx)
+		}, "invalid transaction signature"},
+		{"address", func(t *testing.T) *Block {
+			tx := alice.post(t, 2, "second")
+			tx.Receiver = "ab"
+			tx.ID = tx.ComputeID()
+			return bc.mineTestBlock(t, chain, tx)
+		}, "invalid address format"},
+		{"nonce gap", func(t *testing.T) *Block {
+			return bc.mineTestBlock(t, chain, alice.post(t, 3, "third"))
+		}, "invalid nonce"},
+		{"merkle root", func(t *testing.T) *Block {
+			block := bc.mineTestBlock(t, chain, alice.post(t, 2, "second"))
+			block.MerkleRoot = computeMerkleRoot([]Transaction{alice.post(t, 2, "other")})
+			bc.sealTestBlock(t, chain, block)
+			return block
+		}, "invalid merkle root"},
+		{"timestamp at median time", func(t *testing.T) *Block {
+			block := bc.mineTestBlock(t, chain, alice.post(t, 2, "second"))
+			block.Timestamp = medianTime(chain)
+			bc.sealTestBlock(t, chain, block)
+			return block
+		}, "is not after the median time"},
+		{"timestamp before median time", func(t *testing.T) *Block {
+			block := bc.mineTestBlock(t, chain, alice.post(t, 2, "second"))
+			block.Timestamp = medianTime(chain).Add(-time.Second)
+			bc.sealTestBlock(t, chain, block)
+			return block
+		}, "is not after the median time"},
+	}
+
+	// 未被修改的区块可以通过验证，确保每个用例只因被修改的字段失败
+	if err := bc.ValidateChain(append(chain, bc.mineTestBlock(t, chain, alice.post(t, 2, "second")))); err != nil {
+		t.Fatalf("ValidateChain(valid block) = %v", err)
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			candidate := append(append([]*Block(nil), chain...), tt.block(t))
+			err := bc.ValidateChain(candidate)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("ValidateChain = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
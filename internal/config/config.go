@@ -16,9 +16,41 @@ type Config struct {
 	} `yaml:"database"`
 
 	Blockchain struct {
-		Difficulty  int    `yaml:"difficulty"`
-		NodeAddress string `yaml:"node_address"`
+		Difficulty       int    `yaml:"difficulty"`        // 起始难度（创世块难度）
+		RetargetInterval int    `yaml:"retarget_interval"` // 每隔多少个区块调整一次难度，0 表示不调整
+		TargetBlockTime  int    `yaml:"target_block_time"` // 目标出块时间（秒）
+		NodeAddress      string `yaml:"node_address"`
+		ChainID          string `yaml:"chain_id"`   // 链 ID，参与交易签名以防跨网络重放，默认 twichain
+		MainSpace        string `yaml:"main_space"` // 主空间地址，发往该地址的帖子出现在主空间，创世帖子也发往这里
 	} `yaml:"blockchain"`
+
+	Mining struct {
+		BlockInterval        int  `yaml:"block_interval"`         // 定时出块间隔（秒），默认 60
+		MempoolThreshold     int  `yaml:"mempool_threshold"`      // 交易池达到该数量时立即出块，0 表示不启用
+		MaxBlockTransactions int  `yaml:"max_block_transactions"` // 每个区块最多打包的交易数，0 表示不限制
+		EmptyBlocks          bool `yaml:"empty_blocks"`           // 没有交易时是否出心跳空块
+		Workers              int  `yaml:"workers"`                // 工作量证明并行搜索的协程数，0 表示使用 CPU 核数
+	} `yaml:"mining"`
+
+	Mempool struct {
+		MaxSize      int `yaml:"max_size"`       // 交易池最多容纳的交易数，0 表示不限制
+		MaxPerSender int `yaml:"max_per_sender"` // 每个发送者最多的待打包交易数，0 表示不限制
+		TTL          int `yaml:"ttl"`            // 交易在池中的最长存活时间（秒），0 表示不过期
+	} `yaml:"mempool"`
+
+	Threads struct {
+		MaxDepth    int `yaml:"max_depth"`     // 讨论串评论树的最大展开深度，默认 5
+		PageSize    int `yaml:"page_size"`     // 每个帖子或评论默认展开的回复数，默认 20
+		MaxPageSize int `yaml:"max_page_size"` // 每个帖子或评论最多展开的回复数，默认 100
+		MaxComments int `yaml:"max_comments"`  // 一次请求最多返回的评论总数，默认 500
+	} `yaml:"threads"`
+
+	Consensus struct {
+		Engine       string   `yaml:"engine"`        // 共识引擎：pow（默认）或 poa
+		Validators   []string `yaml:"validators"`    // PoA 验证者公钥列表，按顺序轮流出块
+		ValidatorKey string   `yaml:"validator_key"` // 本节点的 PoA 验证者私钥，为空则只同步不出块
+		BackupDelay  int      `yaml:"backup_delay"`  // PoA 轮值验证者超过多少秒未出块时由下一个验证者代出，每多一个顺位再等这么久，0 表示不启用
+	} `yaml:"consensus"`
 }
 
 func LoadConfig(filename string) (*Config, error) {
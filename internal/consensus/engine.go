@@ -0,0 +1,115 @@
+package consensus
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"time"
+
+	"twichain/internal/config"
+	"twichain/internal/crypto"
+)
+
+// 区块头编码版本
+const (
+	// VersionLegacy 旧区块：哈希为整个区块的 JSON，工作量证明为十进制字符串拼接
+	VersionLegacy = 0
+	// VersionCanonical 区块头使用规范编码，时间戳为 Unix 纳秒整数
+	VersionCanonical = 1
+	// VersionSealed 在规范编码中加入出块者和封装签名，供 PoA 等签名类共识使用
+	VersionSealed = 2
+	// VersionContentID 区块头编码与版本 2 相同，要求所有交易 ID 为内容寻址的哈希
+	VersionContentID = 3
+	// VersionEnvelope 区块头编码与版本 2 相同，要求所有交易的签名覆盖完整交易信封
+	VersionEnvelope = 4
+	// VersionProofRoot 区块头编码与版本 2 相同，工作量证明的哈希输入加入 Merkle 根，替换交易后原有的 proof 失效
+	VersionProofRoot = 5
+	// VersionCurrent 新区块使用的版本
+	VersionCurrent = VersionProofRoot
+)
+
+// 支持的共识引擎名称
+const (
+	EnginePoW = "pow"
+	EnginePoA = "poa"
+)
+
+// Header 区块头，共识引擎只依赖区块头完成封装和验证
+type Header struct {
+	Version    int       `json:"version"`
+	Index      int       `json:"index"`
+	Timestamp  time.Time `json:"timestamp"`
+	PrevHash   string    `json:"previous_hash"`
+	MerkleRoot string    `json:"merkle_root"`
+	Proof      int64     `json:"proof"`
+	Difficulty int       `json:"difficulty"`
+	Validator  string    `json:"validator"` // 出块验证者公钥（PoA）
+	Seal       string    `json:"seal"`      // 出块验证者对区块头的签名（PoA）
+}
+
+// CanonicalBytes 返回区块头的规范编码，字段顺序固定：
+// version, index, timestamp(unix nano), previous_hash, merkle_root, proof, difficulty
+// 版本 2 起追加 validator, seal
+func (h Header) CanonicalBytes() []byte {
+	e := crypto.NewEncoder(uint8(h.Version)).
+		WriteInt64(int64(h.Index)).
+		WriteInt64(h.Timestamp.UnixNano()).
+		WriteString(h.PrevHash).
+		WriteString(h.MerkleRoot).
+		WriteInt64(h.Proof).
+		WriteInt64(int64(h.Difficulty))
+	if h.Version >= VersionSealed {
+		e.WriteString(h.Validator).WriteString(h.Seal)
+	}
+	return e.Bytes()
+}
+
+// unsealed 返回去掉封装签名的区块头，作为签名内容
+func (h Header) unsealed() Header {
+	h.Seal = ""
+	return h
+}
+
+// ChainReader 供共识引擎按位置读取已有链的区块头，最后一个即为新区块的父区块
+type ChainReader interface {
+	Len() int
+	HeaderAt(i int) *Header
+}
+
+// Engine 可插拔的共识引擎
+type Engine interface {
+	// Name 返回引擎名称
+	Name() string
+	// Prepare 为新区块头填充共识字段（难度、出块者等）
+	Prepare(chain ChainReader, header *Header) error
+	// Seal 为区块头生成共识证明（工作量证明或验证者签名），ctx 取消时放弃并返回 ctx.Err()
+	Seal(ctx context.Context, chain ChainReader, header *Header) error
+	// VerifyHeader 验证区块头是否满足共识规则，chain 为父区块及之前的链
+	VerifyHeader(chain ChainReader, header *Header) error
+	// Weight 返回区块对分叉选择的权重，累计权重最大的链获胜
+	Weight(header *Header) *big.Int
+}
+
+// ErrNotInTurn 表示当前节点不是该高度的出块者
+var ErrNotInTurn = fmt.Errorf("not in turn to seal this block")
+
+// NewEngine 根据配置创建共识引擎，未配置时默认使用工作量证明
+func NewEngine(cfg *config.Config) (Engine, error) {
+	switch cfg.Consensus.Engine {
+	case "", EnginePoW:
+		return NewProofOfWork(
+			cfg.Blockchain.Difficulty,
+			cfg.Blockchain.RetargetInterval,
+			time.Duration(cfg.Blockchain.TargetBlockTime)*time.Second,
+			cfg.Mining.Workers,
+		), nil
+	case EnginePoA:
+		return NewProofOfAuthority(
+			cfg.Consensus.Validators,
+			cfg.Consensus.ValidatorKey,
+			time.Duration(cfg.Consensus.BackupDelay)*time.Second,
+		)
+	default:
+		return nil, fmt.Errorf("unknown consensus engine: %s", cfg.Consensus.Engine)
+	}
+}
@@ -0,0 +1,165 @@
+package consensus
+
+import (
+	"context"
+	"crypto/ed25519"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"time"
+
+	"twichain/internal/crypto"
+)
+
+// ProofOfAuthority 权威证明：配置中的 ed25519 验证者按区块高度轮流签名出块
+// 高度为 index 的区块由轮值验证者 validators[index % len(validators)] 签名；
+// 轮值验证者离线时，其后第 k 个验证者在父区块之后 k*backupDelay 起可以代为出块，
+// 代出的区块权重低于轮值区块，轮值验证者恢复后它出的分叉胜出
+type ProofOfAuthority struct {
+	validators  []string      // 验证者公钥（十六进制），所有节点的配置和顺序必须一致
+	privateKey  string        // 本节点验证者私钥，为空时只验证不出块
+	publicKey   string        // 本节点验证者公钥
+	backupDelay time.Duration // 后备验证者每个顺位的等待时间，0 表示只允许轮值验证者出块，所有节点必须一致
+}
+
+// NewProofOfAuthority 创建权威证明引擎
+func NewProofOfAuthority(validators []string, privateKey string, backupDelay time.Duration) (*ProofOfAuthority, error) {
+	if len(validators) == 0 {
+		return nil, fmt.Errorf("proof of authority requires at least one validator")
+	}
+	for _, validator := range validators {
+		if !crypto.ValidateAddress(validator) {
+			return nil, fmt.Errorf("invalid validator public key: %s", validator)
+		}
+	}
+
+	if backupDelay < 0 {
+		return nil, fmt.Errorf("invalid backup delay: %s", backupDelay)
+	}
+
+	p := &ProofOfAuthority{validators: validators, backupDelay: backupDelay}
+	if privateKey == "" {
+		return p, nil
+	}
+
+	privBytes, err := hex.DecodeString(privateKey)
+	if err != nil || len(privBytes) != ed25519.PrivateKeySize {
+		return nil, fmt.Errorf("invalid validator private key: must be %d-byte hex", ed25519.PrivateKeySize)
+	}
+	p.privateKey = privateKey
+	p.publicKey = hex.EncodeToString(ed25519.PrivateKey(privBytes).Public().(ed25519.PublicKey))
+
+	if !p.isValidator(p.publicKey) {
+		return nil, fmt.Errorf("validator key %s is not in the validator set", p.publicKey)
+	}
+	return p, nil
+}
+
+func (p *ProofOfAuthority) Name() string {
+	return EnginePoA
+}
+
+// Prepare 写入该高度的出块者：轮到本节点，或者轮值验证者等待超时、本节点可以作为后备出块时写入本节点，
+// 否则写入轮值验证者，创世块不需要验证者
+func (p *ProofOfAuthority) Prepare(chain ChainReader, header *Header) error {
+	if chain.Len() == 0 {
+		return nil
+	}
+	header.Difficulty = 0
+	header.Proof = 0
+	header.Validator = p.inTurn(header.Index)
+	if p.publicKey != "" && p.maySeal(chain.HeaderAt(chain.Len()-1), header, p.publicKey) {
+		header.Validator = p.publicKey
+	}
+	return nil
+}
+
+// Seal 使用本节点验证者私钥签名区块头，未轮到本节点时返回 ErrNotInTurn
+func (p *ProofOfAuthority) Seal(ctx context.Context, chain ChainReader, header *Header) error {
+	if p.privateKey == "" || header.Validator != p.publicKey {
+		return ErrNotInTurn
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	seal, err := crypto.SignCanonical(p.privateKey, header.unsealed())
+	if err != nil {
+		return fmt.Errorf("failed to seal block: %v", err)
+	}
+	header.Seal = seal
+	return nil
+}
+
+// VerifyHeader 验证出块者是否轮到该高度或已过其后备等待时间，以及签名是否有效
+func (p *ProofOfAuthority) VerifyHeader(chain ChainReader, header *Header) error {
+	if chain.Len() == 0 {
+		return fmt.Errorf("missing parent block")
+	}
+	if header.Version < VersionSealed {
+		return fmt.Errorf("block version %d cannot carry a validator seal", header.Version)
+	}
+	if header.Proof != 0 || header.Difficulty != 0 {
+		return fmt.Errorf("unexpected proof of work in proof-of-authority block")
+	}
+
+	if !p.maySeal(chain.HeaderAt(chain.Len()-1), header, header.Validator) {
+		return fmt.Errorf("invalid validator: expected %s, got %s", p.inTurn(header.Index), header.Validator)
+	}
+
+	valid, err := crypto.VerifyCanonical(header.Validator, header.unsealed(), header.Seal)
+	if err != nil {
+		return fmt.Errorf("invalid seal: %v", err)
+	}
+	if !valid {
+		return fmt.Errorf("invalid seal")
+	}
+	return nil
+}
+
+// Weight 轮值验证者出的区块权重为 2，后备验证者代出的区块权重为 1
+// 同样长度的分叉中轮值区块多的胜出，轮值验证者恢复后链会回到轮值出块
+func (p *ProofOfAuthority) Weight(header *Header) *big.Int {
+	if header.Validator == p.inTurn(header.Index) {
+		return big.NewInt(2)
+	}
+	return big.NewInt(1)
+}
+
+// inTurn 返回该高度应出块的验证者
+func (p *ProofOfAuthority) inTurn(index int) string {
+	return p.validators[index%len(p.validators)]
+}
+
+// backupRank 返回验证者在该高度的出块顺位：0 为轮值验证者，k 为其后第 k 个验证者，不在验证者集合中返回 -1
+func (p *ProofOfAuthority) backupRank(index int, validator string) int {
+	n := len(p.validators)
+	for i, v := range p.validators {
+		if v == validator {
+			return ((i-index)%n + n) % n
+		}
+	}
+	return -1
+}
+
+// maySeal 判断验证者能否在 parent 之后出该区块：轮值验证者随时可以出块，
+// 第 k 顺位的后备验证者要求区块时间戳不早于父区块时间戳加 k*backupDelay
+func (p *ProofOfAuthority) maySeal(parent, header *Header, validator string) bool {
+	rank := p.backupRank(header.Index, validator)
+	switch {
+	case rank == 0:
+		return true
+	case rank < 0 || p.backupDelay <= 0:
+		return false
+	}
+	return !header.Timestamp.Before(parent.Timestamp.Add(time.Duration(rank) * p.backupDelay))
+}
+
+func (p *ProofOfAuthority) isValidator(publicKey string) bool {
+	for _, validator := range p.validators {
+		if validator == publicKey {
+			return true
+		}
+	}
+	return false
+}
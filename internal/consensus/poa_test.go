@@ -0,0 +1,239 @@
+package consensus
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"encoding/hex"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+
+	"twichain/internal/crypto"
+)
+
+const testBackupDelay = 10 * time.Second
+
+// testValidator 测试用的 ed25519 验证者密钥
+type testValidator struct {
+	public  string
+	private string
+}
+
+func newTestValidator(seed byte) testValidator {
+	key := ed25519.NewKeyFromSeed(bytes.Repeat([]byte{seed}, ed25519.SeedSize))
+	return testValidator{
+		public:  hex.EncodeToString(key.Public().(ed25519.PublicKey)),
+		private: hex.EncodeToString(key),
+	}
+}
+
+// newTestAuthorities 创建三个验证者和各自持有私钥的引擎
+func newTestAuthorities(t *testing.T) ([]testValidator, []*ProofOfAuthority) {
+	t.Helper()
+	validators := []testValidator{newTestValidator(1), newTestValidator(2), newTestValidator(3)}
+	publicKeys := make([]string, len(validators))
+	for i, v := range validators {
+		publicKeys[i] = v.public
+	}
+	engines := make([]*ProofOfAuthority, len(validators))
+	for i, v := range validators {
+		engine, err := NewProofOfAuthority(publicKeys, v.private, testBackupDelay)
+		if err != nil {
+			t.Fatalf("NewProofOfAuthority: %v", err)
+		}
+		engines[i] = engine
+	}
+	return validators, engines
+}
+
+func TestBackupRank(t *testing.T) {
+	validators, engines := newTestAuthorities(t)
+	p := engines[0]
+
+	tests := []struct {
+		index     int
+		validator string
+		want      int
+	}{
+		{0, validators[0].public, 0},
+		{0, validators[1].public, 1},
+		{0, validators[2].public, 2},
+		{1, validators[1].public, 0},
+		{1, validators[2].public, 1},
+		{1, validators[0].public, 2},
+		{5, validators[2].public, 0},
+		{5, validators[0].public, 1},
+		{1, newTestValidator(9).public, -1},
+	}
+	for _, tt := range tests {
+		if got := p.backupRank(tt.index, tt.validator); got != tt.want {
+			t.Errorf("backupRank(%d, validator %s) = %d, want %d", tt.index, tt.validator[:8], got, tt.want)
+		}
+	}
+}
+
+func TestMaySeal(t *testing.T) {
+	validators, engines := newTestAuthorities(t)
+	p := engines[0]
+	parent := &Header{Index: 1, Timestamp: testStart}
+	at := func(delay time.Duration) *Header {
+		return &Header{Index: 2, Timestamp: testStart.Add(delay)}
+	}
+
+	// 高度 2 的轮值验证者为 validators[2]，后备顺位依次为 validators[0]、validators[1]
+	tests := []struct {
+		name      string
+		validator string
+		delay     time.Duration
+		want      bool
+	}{
+		{"in turn immediately", validators[2].public, 0, true},
+		{"first backup before delay", validators[0].public, testBackupDelay - time.Second, false},
+		{"first backup at delay", validators[0].public, testBackupDelay, true},
+		{"second backup after one delay", validators[1].public, testBackupDelay, false},
+		{"second backup at two delays", validators[1].public, 2 * testBackupDelay, true},
+		{"unknown validator", newTestValidator(9).public, time.Hour, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := p.maySeal(parent, at(tt.delay), tt.validator); got != tt.want {
+				t.Errorf("maySeal = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	// backupDelay 为 0 时只有轮值验证者可以出块
+	strict, err := NewProofOfAuthority(p.validators, "", 0)
+	if err != nil {
+		t.Fatalf("NewProofOfAuthority: %v", err)
+	}
+	if strict.maySeal(parent, at(time.Hour), validators[0].public) {
+		t.Errorf("backup validator may seal with backup disabled")
+	}
+	if !strict.maySeal(parent, at(0), validators[2].public) {
+		t.Errorf("in-turn validator may not seal with backup disabled")
+	}
+}
+
+func TestProofOfAuthorityWeight(t *testing.T) {
+	validators, engines := newTestAuthorities(t)
+	p := engines[0]
+
+	if got := p.Weight(&Header{Index: 2, Validator: validators[2].public}); got.Cmp(big.NewInt(2)) != 0 {
+		t.Errorf("Weight(in turn) = %s, want 2", got)
+	}
+	if got := p.Weight(&Header{Index: 2, Validator: validators[0].public}); got.Cmp(big.NewInt(1)) != 0 {
+		t.Errorf("Weight(backup) = %s, want 1", got)
+	}
+}
+
+func TestProofOfAuthorityPrepareUsesBackupAfterDelay(t *testing.T) {
+	validators, engines := newTestAuthorities(t)
+	chain := testChain{{Version: VersionCurrent, Index: 1, Timestamp: testStart}}
+
+	// validators[0] 是高度 2 的第一顺位后备，等待时间未到时写入轮值验证者，不能出块
+	header := &Header{Version: VersionCurrent, Index: 2, Timestamp: testStart.Add(time.Second)}
+	if err := engines[0].Prepare(chain, header); err != nil {
+		t.Fatalf("Prepare: %v", err)
+	}
+	if header.Validator != validators[2].public {
+		t.Errorf("Prepare before backup delay chose %s, want the in-turn validator", header.Validator[:8])
+	}
+	if err := engines[0].Seal(context.Background(), chain, header); err != ErrNotInTurn {
+		t.Errorf("Seal before backup delay = %v, want ErrNotInTurn", err)
+	}
+
+	header = &Header{Version: VersionCurrent, Index: 2, Timestamp: testStart.Add(testBackupDelay)}
+	if err := engines[0].Prepare(chain, header); err != nil {
+		t.Fatalf("Prepare: %v", err)
+	}
+	if header.Validator != validators[0].public {
+		t.Errorf("Prepare after backup delay chose %s, want the backup validator", header.Validator[:8])
+	}
+	if err := engines[0].Seal(context.Background(), chain, header); err != nil {
+		t.Fatalf("Seal after backup delay: %v", err)
+	}
+	if err := engines[1].VerifyHeader(chain, header); err != nil {
+		t.Errorf("VerifyHeader(backup block) = %v", err)
+	}
+}
+
+func TestProofOfAuthorityVerifyHeader(t *testing.T) {
+	validators, engines := newTestAuthorities(t)
+	verifier := engines[1]
+	chain := testChain{{Version: VersionCurrent, Index: 1, Timestamp: testStart}}
+
+	// sealed 返回由 signer 签名的高度 2 区块头，出块时间为父区块之后 delay
+	sealed := func(t *testing.T, signer testValidator, delay time.Duration) *Header {
+		t.Helper()
+		header := &Header{
+			Version:   VersionCurrent,
+			Index:     2,
+			Timestamp: testStart.Add(delay),
+			PrevHash:  "parent",
+			Validator: signer.public,
+		}
+		seal, err := crypto.SignCanonical(signer.private, header.unsealed())
+		if err != nil {
+			t.Fatalf("SignCanonical: %v", err)
+		}
+		header.Seal = seal
+		return header
+	}
+
+	if err := verifier.VerifyHeader(chain, sealed(t, validators[2], time.Second)); err != nil {
+		t.Fatalf("VerifyHeader(in turn) = %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		header func(t *testing.T) *Header
+		chain  testChain
+		want   string
+	}{
+		{"out of turn before backup delay", func(t *testing.T) *Header {
+			return sealed(t, validators[0], time.Second)
+		}, chain, "invalid validator"},
+		{"unknown validator", func(t *testing.T) *Header {
+			return sealed(t, newTestValidator(9), time.Hour)
+		}, chain, "invalid validator"},
+		{"missing seal", func(t *testing.T) *Header {
+			header := sealed(t, validators[2], time.Second)
+			header.Seal = ""
+			return header
+		}, chain, "invalid seal"},
+		{"seal by another validator", func(t *testing.T) *Header {
+			header := sealed(t, validators[2], time.Second)
+			header.Seal = sealed(t, validators[0], time.Second).Seal
+			return header
+		}, chain, "invalid seal"},
+		{"header changed after sealing", func(t *testing.T) *Header {
+			header := sealed(t, validators[2], time.Second)
+			header.MerkleRoot = "changed"
+			return header
+		}, chain, "invalid seal"},
+		{"proof of work fields", func(t *testing.T) *Header {
+			header := sealed(t, validators[2], time.Second)
+			header.Difficulty = 1
+			return header
+		}, chain, "unexpected proof of work"},
+		{"unsealed version", func(t *testing.T) *Header {
+			header := sealed(t, validators[2], time.Second)
+			header.Version = VersionCanonical
+			return header
+		}, chain, "cannot carry a validator seal"},
+		{"missing parent", func(t *testing.T) *Header {
+			return sealed(t, validators[2], time.Second)
+		}, nil, "missing parent block"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := verifier.VerifyHeader(tt.chain, tt.header(t))
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("VerifyHeader = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,248 @@
+package consensus
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math/big"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"twichain/internal/crypto"
+)
+
+const (
+	// legacyDifficulty 是引入区块难度字段之前固定使用的难度
+	legacyDifficulty = 2
+	// maxDifficulty 限制难度上限，避免出块停滞
+	maxDifficulty = 32
+	// retargetFactor 实际出块时间偏离目标多少倍时调整难度（每级难度相差 16 倍）
+	retargetFactor = 4
+	// cancelCheckInterval 每个工作协程尝试多少次检查一次是否被取消
+	cancelCheckInterval = 1024
+)
+
+// ProofOfWork 工作量证明：sha256(上一区块 proof, proof, 上一区块哈希, Merkle 根) 需要以 difficulty 个 0 开头
+type ProofOfWork struct {
+	initialDifficulty int           // 起始难度（创世块难度）
+	retargetInterval  int           // 难度调整周期（区块数）
+	targetBlockTime   time.Duration // 目标出块时间
+	workers           int           // 并行搜索的工作协程数
+
+	mu       sync.Mutex
+	hashRate float64 // 最近一次搜索的算力（次/秒）
+}
+
+// NewProofOfWork 创建工作量证明引擎，workers 不大于 0 时使用 CPU 核数
+func NewProofOfWork(initialDifficulty, retargetInterval int, targetBlockTime time.Duration, workers int) *ProofOfWork {
+	if initialDifficulty <= 0 {
+		initialDifficulty = legacyDifficulty
+	}
+	if initialDifficulty > maxDifficulty {
+		log.Printf("Initial difficulty %d exceeds the maximum, using %d", initialDifficulty, maxDifficulty)
+		initialDifficulty = maxDifficulty
+	}
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+	return &ProofOfWork{
+		initialDifficulty: initialDifficulty,
+		retargetInterval:  retargetInterval,
+		targetBlockTime:   targetBlockTime,
+		workers:           workers,
+	}
+}
+
+func (p *ProofOfWork) Name() string {
+	return EnginePoW
+}
+
+// Prepare 写入按历史计算出的难度
+func (p *ProofOfWork) Prepare(chain ChainReader, header *Header) error {
+	header.Difficulty = p.nextDifficulty(chain)
+	return nil
+}
+
+// Seal 并行搜索满足难度的 proof：第 i 个工作协程依次尝试 i, i+workers, i+2*workers...
+// 找到结果或 ctx 被取消（例如链顶变化）时所有工作协程退出
+func (p *ProofOfWork) Seal(ctx context.Context, chain ChainReader, header *Header) error {
+	if chain.Len() == 0 {
+		return fmt.Errorf("cannot seal without a parent block")
+	}
+	parent := chain.HeaderAt(chain.Len() - 1)
+	difficulty := headerDifficulty(header)
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	found := make(chan int64, 1)
+	var hashes atomic.Int64
+	var wg sync.WaitGroup
+	start := time.Now()
+
+	for w := 0; w < p.workers; w++ {
+		wg.Add(1)
+		go func(proof int64) {
+			defer wg.Done()
+			var attempts int64
+			defer func() { hashes.Add(attempts) }()
+
+			for ; ; proof += int64(p.workers) {
+				if attempts%cancelCheckInterval == 0 && ctx.Err() != nil {
+					return
+				}
+				attempts++
+				if ValidProof(parent.Proof, proof, header.PrevHash, header.MerkleRoot, difficulty, header.Version) {
+					select {
+					case found <- proof:
+					default:
+					}
+					cancel()
+					return
+				}
+			}
+		}(int64(w))
+	}
+	wg.Wait()
+
+	elapsed := time.Since(start)
+	p.recordHashRate(hashes.Load(), elapsed)
+
+	select {
+	case proof := <-found:
+		header.Proof = proof
+		log.Printf("Sealed block %d: difficulty %d, %d hashes in %s (%.0f H/s, %d workers)",
+			header.Index, difficulty, hashes.Load(), elapsed.Round(time.Millisecond), p.HashRate(), p.workers)
+		return nil
+	default:
+		return ctx.Err()
+	}
+}
+
+// HashRate 返回最近一次搜索的算力（次/秒）
+func (p *ProofOfWork) HashRate() float64 {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.hashRate
+}
+
+// Workers 返回并行搜索的工作协程数
+func (p *ProofOfWork) Workers() int {
+	return p.workers
+}
+
+func (p *ProofOfWork) recordHashRate(hashes int64, elapsed time.Duration) {
+	if elapsed <= 0 {
+		return
+	}
+	p.mu.Lock()
+	p.hashRate = float64(hashes) / elapsed.Seconds()
+	p.mu.Unlock()
+}
+
+// VerifyHeader 验证区块记录的难度与历史一致，且 proof 满足该难度
+func (p *ProofOfWork) VerifyHeader(chain ChainReader, header *Header) error {
+	if chain.Len() == 0 {
+		return fmt.Errorf("missing parent block")
+	}
+	parent := chain.HeaderAt(chain.Len() - 1)
+
+	if header.Validator != "" || header.Seal != "" {
+		return fmt.Errorf("unexpected validator seal in proof-of-work block")
+	}
+
+	// 区块记录的难度必须与按历史计算出的难度一致
+	if expected := p.nextDifficulty(chain); headerDifficulty(header) != expected {
+		return fmt.Errorf("invalid difficulty: expected %d, got %d", expected, header.Difficulty)
+	}
+
+	// 按区块自身记录的难度验证工作量证明
+	if !ValidProof(parent.Proof, header.Proof, header.PrevHash, header.MerkleRoot, headerDifficulty(header), header.Version) {
+		return fmt.Errorf("invalid proof of work: proof %d", header.Proof)
+	}
+	return nil
+}
+
+// Weight 区块的工作量为 16^difficulty
+// 分叉选择在验证其他节点的链之前就会计算权重，难度超过 maxDifficulty 的区块不可能有效，权重记为 0，
+// 避免按对方声称的难度做超大整数运算
+func (p *ProofOfWork) Weight(header *Header) *big.Int {
+	difficulty := headerDifficulty(header)
+	if difficulty > maxDifficulty {
+		return new(big.Int)
+	}
+	return new(big.Int).Exp(big.NewInt(16), big.NewInt(int64(difficulty)), nil)
+}
+
+// ValidProof 按给定难度和区块编码版本验证工作量证明，难度超过哈希长度时无法满足
+func ValidProof(lastProof, proof int64, lastHash, merkleRoot string, difficulty, version int) bool {
+	guessHash := crypto.Hash(proofGuess(version, lastProof, proof, lastHash, merkleRoot))
+	if difficulty > len(guessHash) {
+		return false
+	}
+	zeros := strings.Repeat("0", difficulty)
+	return guessHash[:difficulty] == zeros
+}
+
+// proofGuess 返回工作量证明的哈希输入
+// 旧区块为十进制字符串拼接，规范版本为定长编码，避免 "1"+"23" 与 "12"+"3" 的歧义
+// VersionProofRoot 起追加 Merkle 根，工作量证明绑定区块中的交易
+func proofGuess(version int, lastProof, proof int64, lastHash, merkleRoot string) []byte {
+	if version < VersionCanonical {
+		return []byte(strconv.FormatInt(lastProof, 10) + strconv.FormatInt(proof, 10) + lastHash)
+	}
+	e := crypto.NewEncoder(uint8(version)).
+		WriteInt64(lastProof).
+		WriteInt64(proof).
+		WriteString(lastHash)
+	if version >= VersionProofRoot {
+		e.WriteString(merkleRoot)
+	}
+	return e.Bytes()
+}
+
+// headerDifficulty 返回区块出块时生效的难度，旧区块未记录难度时按 legacyDifficulty 处理
+func headerDifficulty(header *Header) int {
+	if header.Difficulty <= 0 {
+		return legacyDifficulty
+	}
+	return header.Difficulty
+}
+
+// nextDifficulty 根据已有链计算下一个区块应使用的难度
+// 每 retargetInterval 个区块，比较最近一个周期的实际耗时和目标耗时：
+// 过快则难度加一，过慢则难度减一
+func (p *ProofOfWork) nextDifficulty(chain ChainReader) int {
+	length := chain.Len()
+	if length == 0 {
+		return p.initialDifficulty
+	}
+
+	last := chain.HeaderAt(length - 1)
+	difficulty := headerDifficulty(last)
+
+	interval := p.retargetInterval
+	if interval <= 0 || p.targetBlockTime <= 0 || length <= interval {
+		return difficulty
+	}
+	// 只在周期边界上调整
+	if last.Index%interval != 0 {
+		return difficulty
+	}
+
+	first := chain.HeaderAt(length - 1 - interval)
+	actual := last.Timestamp.Sub(first.Timestamp)
+	expected := time.Duration(interval) * p.targetBlockTime
+
+	switch {
+	case actual < expected/retargetFactor && difficulty < maxDifficulty:
+		difficulty++
+	case actual > expected*retargetFactor && difficulty > 1:
+		difficulty--
+	}
+	return difficulty
+}
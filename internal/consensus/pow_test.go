@@ -0,0 +1,151 @@
+package consensus
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testChain 按位置读取区块头的测试链
+type testChain []*Header
+
+func (c testChain) Len() int {
+	return len(c)
+}
+
+func (c testChain) HeaderAt(i int) *Header {
+	return c[i]
+}
+
+var testStart = time.Unix(1700000000, 0)
+
+// spacedChain 创建 n 个难度相同、出块间隔为 spacing 的区块头，索引从 1 开始
+func spacedChain(n, difficulty int, spacing time.Duration) testChain {
+	chain := make(testChain, n)
+	for i := range chain {
+		chain[i] = &Header{
+			Version:    VersionCurrent,
+			Index:      i + 1,
+			Timestamp:  testStart.Add(time.Duration(i) * spacing),
+			Difficulty: difficulty,
+		}
+	}
+	return chain
+}
+
+func TestNextDifficulty(t *testing.T) {
+	// 每 4 个区块调整一次，目标周期耗时 40 秒；快于 10 秒加一，慢于 160 秒减一
+	p := NewProofOfWork(3, 4, 10*time.Second, 1)
+
+	tests := []struct {
+		name  string
+		chain testChain
+		want  int
+	}{
+		{"empty chain", nil, 3},
+		{"first interval", spacedChain(4, 3, time.Second), 3},
+		{"not on interval boundary", spacedChain(7, 3, time.Second), 3},
+		{"too fast", spacedChain(8, 3, 2*time.Second), 4},
+		{"fast edge of deadband", spacedChain(8, 3, 2500*time.Millisecond), 3},
+		{"on target", spacedChain(8, 3, 10*time.Second), 3},
+		{"slow edge of deadband", spacedChain(8, 3, 40*time.Second), 3},
+		{"too slow", spacedChain(8, 3, 41*time.Second), 2},
+		{"capped at max difficulty", spacedChain(8, maxDifficulty, time.Second), maxDifficulty},
+		{"floored at one", spacedChain(8, 1, time.Hour), 1},
+		{"legacy header without difficulty", spacedChain(5, 0, time.Second), legacyDifficulty},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := p.nextDifficulty(tt.chain); got != tt.want {
+				t.Errorf("nextDifficulty = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNextDifficultyWithoutRetarget(t *testing.T) {
+	for _, p := range []*ProofOfWork{
+		NewProofOfWork(3, 0, 10*time.Second, 1),
+		NewProofOfWork(3, 4, 0, 1),
+	} {
+		if got := p.nextDifficulty(spacedChain(8, 3, time.Millisecond)); got != 3 {
+			t.Errorf("nextDifficulty with retargeting disabled = %d, want 3", got)
+		}
+	}
+}
+
+func TestNewProofOfWorkCapsInitialDifficulty(t *testing.T) {
+	if got := NewProofOfWork(maxDifficulty+1, 0, 0, 1).nextDifficulty(testChain(nil)); got != maxDifficulty {
+		t.Errorf("initial difficulty = %d, want %d", got, maxDifficulty)
+	}
+}
+
+func TestProofOfWorkWeight(t *testing.T) {
+	p := NewProofOfWork(1, 0, 0, 1)
+	tests := []struct {
+		difficulty int
+		want       *big.Int
+	}{
+		{1, big.NewInt(16)},
+		{0, big.NewInt(256)}, // 旧区块按 legacyDifficulty 计算
+		{maxDifficulty, new(big.Int).Lsh(big.NewInt(1), 4*maxDifficulty)},
+		{maxDifficulty + 1, new(big.Int)},
+		{1 << 30, new(big.Int)},
+	}
+	for _, tt := range tests {
+		if got := p.Weight(&Header{Difficulty: tt.difficulty}); got.Cmp(tt.want) != 0 {
+			t.Errorf("Weight(difficulty %d) = %s, want %s", tt.difficulty, got, tt.want)
+		}
+	}
+}
+
+func TestValidProofRejectsDifficultyAboveHashLength(t *testing.T) {
+	if ValidProof(1, 1, "hash", "root", 65, VersionCurrent) {
+		t.Errorf("ValidProof accepted a difficulty longer than the hash")
+	}
+}
+
+func TestProofOfWorkCoversMerkleRoot(t *testing.T) {
+	p := NewProofOfWork(2, 0, 0, 1)
+	chain := testChain{{Version: VersionCurrent, Index: 1, Timestamp: testStart, Proof: 100, Difficulty: 2}}
+
+	seal := func(t *testing.T, version int) *Header {
+		t.Helper()
+		header := &Header{
+			Version:    version,
+			Index:      2,
+			Timestamp:  testStart.Add(time.Minute),
+			PrevHash:   "parent",
+			MerkleRoot: "root",
+		}
+		if err := p.Prepare(chain, header); err != nil {
+			t.Fatalf("Prepare: %v", err)
+		}
+		if err := p.Seal(context.Background(), chain, header); err != nil {
+			t.Fatalf("Seal: %v", err)
+		}
+		if err := p.VerifyHeader(chain, header); err != nil {
+			t.Fatalf("VerifyHeader(sealed) = %v", err)
+		}
+		return header
+	}
+
+	// 难度 2 时随机 Merkle 根约 1/256 的概率仍满足难度，逐个尝试直到找到不满足的
+	header := seal(t, VersionProofRoot)
+	for i := 0; p.VerifyHeader(chain, header) == nil; i++ {
+		header.MerkleRoot = fmt.Sprintf("changed-%d", i)
+	}
+	if err := p.VerifyHeader(chain, header); !strings.Contains(err.Error(), "invalid proof of work") {
+		t.Errorf("VerifyHeader(changed merkle root) = %v, want invalid proof of work", err)
+	}
+
+	// 旧版本的工作量证明不覆盖 Merkle 根，已有区块仍按原规则验证
+	legacy := seal(t, VersionEnvelope)
+	legacy.MerkleRoot = "changed"
+	if err := p.VerifyHeader(chain, legacy); err != nil {
+		t.Errorf("VerifyHeader(version %d, changed merkle root) = %v, want nil", VersionEnvelope, err)
+	}
+}
@@ -0,0 +1,62 @@
+package crypto
+
+import (
+	"bytes"
+	"encoding/binary"
+)
+
+// Canonical 由需要确定性编码的结构实现（区块头、交易等）
+// 编码结果与 JSON 序列化、时区和存储格式无关，可以在不同节点和语言之间复现
+type Canonical interface {
+	CanonicalBytes() []byte
+}
+
+// Encoder 按固定字段顺序生成规范编码：
+// 整数为 8 字节大端序，字符串为 4 字节大端序长度前缀加 UTF-8 字节，布尔值为单字节 0/1
+type Encoder struct {
+	buf bytes.Buffer
+}
+
+// NewEncoder 创建编码器，首字节写入编码版本号
+func NewEncoder(version uint8) *Encoder {
+	e := &Encoder{}
+	e.buf.WriteByte(version)
+	return e
+}
+
+// WriteInt64 写入 8 字节大端序整数
+func (e *Encoder) WriteInt64(v int64) *Encoder {
+	return e.WriteUint64(uint64(v))
+}
+
+// WriteUint64 写入 8 字节大端序无符号整数
+func (e *Encoder) WriteUint64(v uint64) *Encoder {
+	var b [8]byte
+	binary.BigEndian.PutUint64(b[:], v)
+	e.buf.Write(b[:])
+	return e
+}
+
+// WriteString 写入带长度前缀的字符串
+func (e *Encoder) WriteString(s string) *Encoder {
+	var b [4]byte
+	binary.BigEndian.PutUint32(b[:], uint32(len(s)))
+	e.buf.Write(b[:])
+	e.buf.WriteString(s)
+	return e
+}
+
+// WriteBool 写入单字节布尔值
+func (e *Encoder) WriteBool(v bool) *Encoder {
+	if v {
+		e.buf.WriteByte(1)
+	} else {
+		e.buf.WriteByte(0)
+	}
+	return e
+}
+
+// Bytes 返回编码结果
+func (e *Encoder) Bytes() []byte {
+	return e.buf.Bytes()
+}
@@ -14,7 +14,12 @@ func Hash(data []byte) string {
 }
 
 // HashBlock 计算区块的哈希值
+// 实现了 Canonical 的值使用规范编码，其余按 JSON 序列化（仅用于旧版本区块）
 func HashBlock(block interface{}) string {
+	if c, ok := block.(Canonical); ok {
+		return Hash(c.CanonicalBytes())
+	}
+
 	blockBytes, err := json.Marshal(block)
 	if err != nil {
 		return ""
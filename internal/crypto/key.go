@@ -23,6 +23,10 @@ func Sign(privateKey string, message []byte) (string, error) {
 		return "", fmt.Errorf("invalid private key: %v", err)
 	}
 
+	if len(privBytes) != ed25519.PrivateKeySize {
+		return "", fmt.Errorf("invalid private key length: %d bytes", len(privBytes))
+	}
+
 	priv := ed25519.PrivateKey(privBytes)
 	signature := ed25519.Sign(priv, message)
 	return hex.EncodeToString(signature), nil
@@ -50,9 +54,27 @@ func Verify(publicKey string, message []byte, signature string) (bool, error) {
 	}
 	// log.Printf("Decoded signature length: %d bytes", len(sigBytes))
 
+	// ed25519.Verify 遇到长度错误的公钥会 panic，签名和公钥都来自外部输入，需要先检查长度
+	if len(pubBytes) != ed25519.PublicKeySize {
+		return false, fmt.Errorf("invalid public key length: %d bytes", len(pubBytes))
+	}
+	if len(sigBytes) != ed25519.SignatureSize {
+		return false, fmt.Errorf("invalid signature length: %d bytes", len(sigBytes))
+	}
+
 	pub := ed25519.PublicKey(pubBytes)
 	result := ed25519.Verify(pub, message, sigBytes)
 	// log.Printf("Verification result: %v", result)
 
 	return result, nil
 }
+
+// SignCanonical 对规范编码进行签名
+func SignCanonical(privateKey string, data Canonical) (string, error) {
+	return Sign(privateKey, data.CanonicalBytes())
+}
+
+// VerifyCanonical 验证规范编码上的签名
+func VerifyCanonical(publicKey string, data Canonical, signature string) (bool, error) {
+	return Verify(publicKey, data.CanonicalBytes(), signature)
+}
@@ -0,0 +1,119 @@
+package crypto
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+)
+
+// 叶子节点和内部节点使用不同前缀，防止把内部节点伪装成叶子（第二原像攻击）
+const (
+	merkleLeafPrefix = 0x00
+	merkleNodePrefix = 0x01
+)
+
+// MerkleProofStep 是 Merkle 证明中的一步：兄弟节点哈希及其位置
+type MerkleProofStep struct {
+	Hash string `json:"hash"` // 兄弟节点哈希（十六进制）
+	Left bool   `json:"left"` // 兄弟节点是否在左侧
+}
+
+// MerkleRoot 根据叶子数据的哈希（十六进制）计算 Merkle 根
+// 层内节点数为奇数时，最后一个节点直接提升到上一层，不做复制
+func MerkleRoot(leaves []string) (string, error) {
+	if len(leaves) == 0 {
+		return Hash(nil), nil
+	}
+
+	level, err := merkleLeaves(leaves)
+	if err != nil {
+		return "", err
+	}
+	for len(level) > 1 {
+		level = merkleNextLevel(level)
+	}
+	return hex.EncodeToString(level[0]), nil
+}
+
+// MerkleProof 生成第 index 个叶子的包含性证明
+func MerkleProof(leaves []string, index int) ([]MerkleProofStep, error) {
+	if index < 0 || index >= len(leaves) {
+		return nil, fmt.Errorf("leaf index %d out of range", index)
+	}
+
+	level, err := merkleLeaves(leaves)
+	if err != nil {
+		return nil, err
+	}
+
+	var proof []MerkleProofStep
+	for len(level) > 1 {
+		sibling := index ^ 1
+		if sibling < len(level) {
+			proof = append(proof, MerkleProofStep{
+				Hash: hex.EncodeToString(level[sibling]),
+				Left: sibling < index,
+			})
+		}
+		level = merkleNextLevel(level)
+		index /= 2
+	}
+	return proof, nil
+}
+
+// VerifyMerkleProof 验证叶子哈希通过证明路径能否得到给定的 Merkle 根
+func VerifyMerkleProof(leaf, root string, proof []MerkleProofStep) bool {
+	leafBytes, err := hex.DecodeString(leaf)
+	if err != nil {
+		return false
+	}
+
+	current := merkleHash(merkleLeafPrefix, leafBytes)
+	for _, step := range proof {
+		sibling, err := hex.DecodeString(step.Hash)
+		if err != nil {
+			return false
+		}
+		if step.Left {
+			current = merkleHash(merkleNodePrefix, sibling, current)
+		} else {
+			current = merkleHash(merkleNodePrefix, current, sibling)
+		}
+	}
+	return hex.EncodeToString(current) == root
+}
+
+// merkleLeaves 解码叶子哈希并加上叶子前缀
+func merkleLeaves(leaves []string) ([][]byte, error) {
+	level := make([][]byte, len(leaves))
+	for i, leaf := range leaves {
+		leafBytes, err := hex.DecodeString(leaf)
+		if err != nil {
+			return nil, fmt.Errorf("invalid leaf hash %d: %v", i, err)
+		}
+		level[i] = merkleHash(merkleLeafPrefix, leafBytes)
+	}
+	return level, nil
+}
+
+// merkleNextLevel 两两合并得到上一层节点
+func merkleNextLevel(level [][]byte) [][]byte {
+	next := make([][]byte, 0, (len(level)+1)/2)
+	for i := 0; i < len(level); i += 2 {
+		if i+1 == len(level) {
+			next = append(next, level[i])
+			continue
+		}
+		next = append(next, merkleHash(merkleNodePrefix, level[i], level[i+1]))
+	}
+	return next
+}
+
+func merkleHash(prefix byte, parts ...[]byte) []byte {
+	hash := sha256.New()
+	hash.Write([]byte{prefix})
+	for _, part := range parts {
+		hash.Write(part)
+	}
+	return hash.Sum(nil)
+}
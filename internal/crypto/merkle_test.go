@@ -0,0 +1,91 @@
+package crypto
+
+import (
+	"fmt"
+	"testing"
+)
+
+// testLeaves 生成 n 个不同的叶子哈希
+func testLeaves(n int) []string {
+	leaves := make([]string, n)
+	for i := range leaves {
+		leaves[i] = Hash([]byte(fmt.Sprintf("tx-%d", i)))
+	}
+	return leaves
+}
+
+func TestMerkleProofRoundTrip(t *testing.T) {
+	// 覆盖单个叶子、完全二叉树以及各层出现奇数节点的情况
+	for n := 1; n <= 9; n++ {
+		leaves := testLeaves(n)
+		root, err := MerkleRoot(leaves)
+		if err != nil {
+			t.Fatalf("MerkleRoot(%d leaves): %v", n, err)
+		}
+
+		for i, leaf := range leaves {
+			proof, err := MerkleProof(leaves, i)
+			if err != nil {
+				t.Fatalf("MerkleProof(%d leaves, %d): %v", n, i, err)
+			}
+			if !VerifyMerkleProof(leaf, root, proof) {
+				t.Errorf("%d leaves: proof for leaf %d does not verify", n, i)
+			}
+
+			// 证明只对自己的叶子有效
+			other := leaves[(i+1)%n]
+			if n > 1 && VerifyMerkleProof(other, root, proof) {
+				t.Errorf("%d leaves: proof for leaf %d verifies leaf %d", n, i, (i+1)%n)
+			}
+		}
+	}
+}
+
+func TestVerifyMerkleProofRejectsTampering(t *testing.T) {
+	leaves := testLeaves(5)
+	root, err := MerkleRoot(leaves)
+	if err != nil {
+		t.Fatalf("MerkleRoot: %v", err)
+	}
+	proof, err := MerkleProof(leaves, 2)
+	if err != nil {
+		t.Fatalf("MerkleProof: %v", err)
+	}
+
+	tamper := func(edit func([]MerkleProofStep)) []MerkleProofStep {
+		copied := append([]MerkleProofStep(nil), proof...)
+		edit(copied)
+		return copied
+	}
+
+	tests := []struct {
+		name  string
+		leaf  string
+		root  string
+		proof []MerkleProofStep
+	}{
+		{"wrong root", leaves[2], Hash([]byte("other")), proof},
+		{"sibling hash changed", leaves[2], root, tamper(func(p []MerkleProofStep) { p[0].Hash = leaves[0] })},
+		{"sibling side flipped", leaves[2], root, tamper(func(p []MerkleProofStep) { p[0].Left = !p[0].Left })},
+		{"step dropped", leaves[2], root, proof[1:]},
+		{"invalid leaf hex", "zz", root, proof},
+		{"invalid sibling hex", leaves[2], root, tamper(func(p []MerkleProofStep) { p[0].Hash = "zz" })},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if VerifyMerkleProof(tt.leaf, tt.root, tt.proof) {
+				t.Errorf("VerifyMerkleProof accepted a tampered proof")
+			}
+		})
+	}
+}
+
+func TestMerkleProofIndexOutOfRange(t *testing.T) {
+	leaves := testLeaves(3)
+	for _, index := range []int{-1, 3} {
+		if _, err := MerkleProof(leaves, index); err == nil {
+			t.Errorf("MerkleProof(3 leaves, %d) succeeded, want error", index)
+		}
+	}
+}
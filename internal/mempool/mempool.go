@@ -0,0 +1,310 @@
+package mempool
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// 交易池拒绝交易的原因
+var (
+	ErrDuplicate   = errors.New("transaction already in pool")
+	ErrPoolFull    = errors.New("transaction pool is full")
+	ErrSenderQuota = errors.New("sender has too many pending transactions")
+	ErrNonceUsed   = errors.New("nonce already used")
+	ErrNonceGap    = errors.New("nonce gap")
+)
+
+// Tx 交易池中的交易需要实现的接口
+// TxNonce 为发送者序号，从 1 开始连续递增，0 表示交易不带序号
+type Tx interface {
+	TxID() string
+	TxSender() string
+	TxSignature() string
+	TxTimestamp() time.Time
+	TxNonce() uint64
+}
+
+// Config 交易池限制，各项为 0 表示不限制
+type Config struct {
+	MaxSize      int           // 交易池最多容纳的交易数
+	MaxPerSender int           // 每个发送者最多的待打包交易数
+	TTL          time.Duration // 交易在池中的最长存活时间，过期后被淘汰
+}
+
+type entry[T Tx] struct {
+	tx    T
+	added time.Time
+}
+
+// Pool 待打包交易池，并发安全
+type Pool[T Tx] struct {
+	mu          sync.Mutex
+	config      Config
+	entries     map[string]*entry[T]         // 交易 ID -> 交易
+	signatures  map[string]string            // 签名 -> 交易 ID
+	senderCount map[string]int               // 发送者 -> 待打包交易数
+	nonces      map[string]map[uint64]string // 发送者 -> 序号 -> 交易 ID
+	now         func() time.Time
+}
+
+// New 创建交易池
+func New[T Tx](config Config) *Pool[T] {
+	return &Pool[T]{
+		config:      config,
+		entries:     make(map[string]*entry[T]),
+		signatures:  make(map[string]string),
+		senderCount: make(map[string]int),
+		nonces:      make(map[string]map[uint64]string),
+		now:         time.Now,
+	}
+}
+
+// Add 加入交易，按 ID、签名和发送者序号去重，并检查容量和发送者配额
+func (p *Pool[T]) Add(tx T) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.pruneLocked()
+
+	if _, ok := p.entries[tx.TxID()]; ok {
+		return ErrDuplicate
+	}
+	if _, ok := p.signatures[tx.TxSignature()]; ok {
+		return ErrDuplicate
+	}
+	if nonce := tx.TxNonce(); nonce > 0 {
+		if _, ok := p.nonces[tx.TxSender()][nonce]; ok {
+			return ErrNonceUsed
+		}
+	}
+	if p.config.MaxSize > 0 && len(p.entries) >= p.config.MaxSize {
+		return ErrPoolFull
+	}
+	if p.config.MaxPerSender > 0 && p.senderCount[tx.TxSender()] >= p.config.MaxPerSender {
+		return ErrSenderQuota
+	}
+
+	p.entries[tx.TxID()] = &entry[T]{tx: tx, added: p.now()}
+	p.signatures[tx.TxSignature()] = tx.TxID()
+	p.senderCount[tx.TxSender()]++
+	if nonce := tx.TxNonce(); nonce > 0 {
+		if p.nonces[tx.TxSender()] == nil {
+			p.nonces[tx.TxSender()] = make(map[uint64]string)
+		}
+		p.nonces[tx.TxSender()][nonce] = tx.TxID()
+	}
+	return nil
+}
+
+// CheckNonce 检查发送者的新交易序号：不能不大于已确认的序号或与待打包交易重复，
+// 也不能跳过已确认和待打包序号中最大值的下一个
+func (p *Pool[T]) CheckNonce(sender string, nonce, confirmed uint64) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.pruneLocked()
+
+	if nonce <= confirmed {
+		return fmt.Errorf("%w: nonce %d, confirmed nonce is %d", ErrNonceUsed, nonce, confirmed)
+	}
+	if _, ok := p.nonces[sender][nonce]; ok {
+		return fmt.Errorf("%w: nonce %d is already pending", ErrNonceUsed, nonce)
+	}
+	if next := max(confirmed, p.maxNonceLocked(sender)) + 1; nonce > next {
+		return fmt.Errorf("%w: nonce %d, expected at most %d", ErrNonceGap, nonce, next)
+	}
+	return nil
+}
+
+// MaxNonce 返回发送者待打包交易的最大序号，没有待打包交易时返回 0
+func (p *Pool[T]) MaxNonce(sender string) uint64 {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.pruneLocked()
+	return p.maxNonceLocked(sender)
+}
+
+// RemoveThroughNonce 移除发送者序号不大于 nonce 的交易，用于清理被已确认交易占用序号的交易
+func (p *Pool[T]) RemoveThroughNonce(sender string, nonce uint64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for n, id := range p.nonces[sender] {
+		if n <= nonce {
+			p.removeLocked(id)
+		}
+	}
+}
+
+// BySender 按序号从小到大返回发送者带序号的待打包交易
+func (p *Pool[T]) BySender(sender string) []T {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.pruneLocked()
+
+	nonces := make([]uint64, 0, len(p.nonces[sender]))
+	for n := range p.nonces[sender] {
+		nonces = append(nonces, n)
+	}
+	sort.Slice(nonces, func(i, j int) bool { return nonces[i] < nonces[j] })
+
+	txs := make([]T, len(nonces))
+	for i, n := range nonces {
+		txs[i] = p.entries[p.nonces[sender][n]].tx
+	}
+	return txs
+}
+
+func (p *Pool[T]) maxNonceLocked(sender string) uint64 {
+	var highest uint64
+	for n := range p.nonces[sender] {
+		highest = max(highest, n)
+	}
+	return highest
+}
+
+// Remove 按 ID 移除交易，不存在的 ID 会被忽略
+func (p *Pool[T]) Remove(ids ...string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for _, id := range ids {
+		p.removeLocked(id)
+	}
+}
+
+// Has 判断交易是否在池中
+func (p *Pool[T]) Has(id string) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	_, ok := p.entries[id]
+	return ok
+}
+
+// Get 按 ID 获取交易
+func (p *Pool[T]) Get(id string) (T, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	e, ok := p.entries[id]
+	if !ok {
+		var zero T
+		return zero, false
+	}
+	return e.tx, true
+}
+
+// Len 返回池中交易数
+func (p *Pool[T]) Len() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.pruneLocked()
+	return len(p.entries)
+}
+
+// Select 按确定性顺序（交易时间戳，其次交易 ID）返回最多 max 笔交易用于出块，max 不大于 0 时返回全部
+// 同一发送者带序号的交易在各自占据的位置上按序号重新排列，保证序号递增
+func (p *Pool[T]) Select(max int) []T {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.pruneLocked()
+
+	txs := make([]T, 0, len(p.entries))
+	for _, e := range p.entries {
+		txs = append(txs, e.tx)
+	}
+	sort.Slice(txs, func(i, j int) bool {
+		ti, tj := txs[i].TxTimestamp(), txs[j].TxTimestamp()
+		if !ti.Equal(tj) {
+			return ti.Before(tj)
+		}
+		return txs[i].TxID() < txs[j].TxID()
+	})
+	orderByNonce(txs)
+
+	if max > 0 && len(txs) > max {
+		txs = txs[:max]
+	}
+	return txs
+}
+
+// Prune 淘汰过期交易，返回淘汰数量
+func (p *Pool[T]) Prune() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return p.pruneLocked()
+}
+
+func (p *Pool[T]) pruneLocked() int {
+	if p.config.TTL <= 0 {
+		return 0
+	}
+
+	deadline := p.now().Add(-p.config.TTL)
+	pruned := 0
+	for id, e := range p.entries {
+		if e.added.Before(deadline) {
+			p.removeLocked(id)
+			pruned++
+		}
+	}
+	return pruned
+}
+
+func (p *Pool[T]) removeLocked(id string) {
+	e, ok := p.entries[id]
+	if !ok {
+		return
+	}
+
+	delete(p.entries, id)
+	delete(p.signatures, e.tx.TxSignature())
+
+	sender := e.tx.TxSender()
+	p.senderCount[sender]--
+	if p.senderCount[sender] <= 0 {
+		delete(p.senderCount, sender)
+	}
+
+	if nonce := e.tx.TxNonce(); nonce > 0 {
+		delete(p.nonces[sender], nonce)
+		if len(p.nonces[sender]) == 0 {
+			delete(p.nonces, sender)
+		}
+	}
+}
+
+// orderByNonce 保持各发送者交易占据的位置不变，在这些位置上按序号从小到大重新排列该发送者的交易
+func orderByNonce[T Tx](txs []T) {
+	positions := make(map[string][]int)
+	for i, tx := range txs {
+		if tx.TxNonce() > 0 {
+			positions[tx.TxSender()] = append(positions[tx.TxSender()], i)
+		}
+	}
+
+	for _, slots := range positions {
+		if len(slots) < 2 {
+			continue
+		}
+		sender := make([]T, len(slots))
+		for i, slot := range slots {
+			sender[i] = txs[slot]
+		}
+		sort.Slice(sender, func(i, j int) bool {
+			return sender[i].TxNonce() < sender[j].TxNonce()
+		})
+		for i, slot := range slots {
+			txs[slot] = sender[i]
+		}
+	}
+}
@@ -0,0 +1,288 @@
+package mempool
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+type testTx struct {
+	id        string
+	sender    string
+	signature string
+	timestamp time.Time
+	nonce     uint64
+}
+
+func (t testTx) TxID() string           { return t.id }
+func (t testTx) TxSender() string       { return t.sender }
+func (t testTx) TxSignature() string    { return t.signature }
+func (t testTx) TxTimestamp() time.Time { return t.timestamp }
+func (t testTx) TxNonce() uint64        { return t.nonce }
+
+var baseTime = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// tx 创建测试交易，签名取 "sig-" + id，时间戳为 baseTime 之后 second 秒
+func tx(id, sender string, nonce uint64, second int) testTx {
+	return testTx{
+		id:        id,
+		sender:    sender,
+		signature: "sig-" + id,
+		timestamp: baseTime.Add(time.Duration(second) * time.Second),
+		nonce:     nonce,
+	}
+}
+
+func ids(txs []testTx) []string {
+	result := make([]string, len(txs))
+	for i, t := range txs {
+		result[i] = t.id
+	}
+	return result
+}
+
+func equalIDs(got, want []string) bool {
+	if len(got) != len(want) {
+		return false
+	}
+	for i := range got {
+		if got[i] != want[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestAdd(t *testing.T) {
+	sameSignature := tx("b", "bob", 1, 0)
+	sameSignature.signature = "sig-a"
+
+	tests := []struct {
+		name   string
+		config Config
+		txs    []testTx
+		errs   []error // 与 txs 一一对应，nil 表示加入成功
+	}{
+		{
+			name: "distinct transactions",
+			txs:  []testTx{tx("a", "alice", 1, 0), tx("b", "bob", 1, 0), tx("c", "alice", 0, 0)},
+			errs: []error{nil, nil, nil},
+		},
+		{
+			name: "duplicate id",
+			txs:  []testTx{tx("a", "alice", 1, 0), tx("a", "alice", 2, 0)},
+			errs: []error{nil, ErrDuplicate},
+		},
+		{
+			name: "duplicate signature",
+			txs:  []testTx{tx("a", "alice", 1, 0), sameSignature},
+			errs: []error{nil, ErrDuplicate},
+		},
+		{
+			name: "nonce already pending",
+			txs:  []testTx{tx("a", "alice", 1, 0), tx("b", "alice", 1, 0), tx("c", "bob", 1, 0)},
+			errs: []error{nil, ErrNonceUsed, nil},
+		},
+		{
+			name: "transactions without nonce never collide",
+			txs:  []testTx{tx("a", "alice", 0, 0), tx("b", "alice", 0, 0)},
+			errs: []error{nil, nil},
+		},
+		{
+			name:   "pool full",
+			config: Config{MaxSize: 2},
+			txs:    []testTx{tx("a", "alice", 1, 0), tx("b", "bob", 1, 0), tx("c", "carol", 1, 0)},
+			errs:   []error{nil, nil, ErrPoolFull},
+		},
+		{
+			name:   "sender quota",
+			config: Config{MaxPerSender: 2},
+			txs:    []testTx{tx("a", "alice", 1, 0), tx("b", "alice", 2, 0), tx("c", "alice", 3, 0), tx("d", "bob", 1, 0)},
+			errs:   []error{nil, nil, ErrSenderQuota, nil},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pool := New[testTx](tt.config)
+			for i, transaction := range tt.txs {
+				if err := pool.Add(transaction); !errors.Is(err, tt.errs[i]) {
+					t.Errorf("Add(%s) = %v, want %v", transaction.id, err, tt.errs[i])
+				}
+			}
+		})
+	}
+}
+
+func TestAddAfterExpiry(t *testing.T) {
+	pool := New[testTx](Config{TTL: time.Minute})
+	now := baseTime
+	pool.now = func() time.Time { return now }
+
+	if err := pool.Add(tx("a", "alice", 1, 0)); err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+	now = now.Add(2 * time.Minute)
+	// 过期交易被淘汰后，ID、签名和序号都可以重新使用
+	if err := pool.Add(tx("a", "alice", 1, 0)); err != nil {
+		t.Errorf("Add after expiry = %v, want nil", err)
+	}
+	if n := pool.Len(); n != 1 {
+		t.Errorf("Len = %d, want 1", n)
+	}
+}
+
+func TestRemove(t *testing.T) {
+	pool := New[testTx](Config{MaxPerSender: 1})
+	if err := pool.Add(tx("a", "alice", 1, 0)); err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+	pool.Remove("a", "unknown")
+	if pool.Has("a") || pool.Len() != 0 {
+		t.Errorf("transaction still in pool after Remove")
+	}
+	// 移除后释放签名、序号和发送者配额
+	if err := pool.Add(tx("a", "alice", 1, 0)); err != nil {
+		t.Errorf("Add after Remove = %v, want nil", err)
+	}
+}
+
+func TestCheckNonce(t *testing.T) {
+	pool := New[testTx](Config{})
+	for _, transaction := range []testTx{tx("a", "alice", 4, 0), tx("b", "alice", 5, 0)} {
+		if err := pool.Add(transaction); err != nil {
+			t.Fatalf("Add(%s): %v", transaction.id, err)
+		}
+	}
+
+	tests := []struct {
+		name      string
+		sender    string
+		nonce     uint64
+		confirmed uint64
+		want      error
+	}{
+		{"next after pending", "alice", 6, 3, nil},
+		{"confirmed", "alice", 3, 3, ErrNonceUsed},
+		{"below confirmed", "alice", 1, 3, ErrNonceUsed},
+		{"pending", "alice", 5, 3, ErrNonceUsed},
+		{"gap after pending", "alice", 7, 3, ErrNonceGap},
+		{"confirmed beyond pending", "alice", 8, 7, nil},
+		{"first for new sender", "bob", 1, 0, nil},
+		{"next after confirmed", "bob", 3, 2, nil},
+		{"gap for new sender", "bob", 2, 0, ErrNonceGap},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := pool.CheckNonce(tt.sender, tt.nonce, tt.confirmed); !errors.Is(err, tt.want) {
+				t.Errorf("CheckNonce(%s, %d, %d) = %v, want %v", tt.sender, tt.nonce, tt.confirmed, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestSelect(t *testing.T) {
+	tests := []struct {
+		name string
+		txs  []testTx
+		max  int
+		want []string
+	}{
+		{
+			name: "timestamp then id",
+			txs:  []testTx{tx("c", "carol", 0, 2), tx("b", "bob", 0, 1), tx("a", "alice", 0, 1)},
+			want: []string{"a", "b", "c"},
+		},
+		{
+			// alice 的序号 2 时间戳更早，与序号 1 交换位置，bob 的交易位置不变
+			name: "sender nonces reordered in place",
+			txs:  []testTx{tx("a2", "alice", 2, 1), tx("b1", "bob", 1, 2), tx("a1", "alice", 1, 3), tx("a3", "alice", 3, 4)},
+			want: []string{"a1", "b1", "a2", "a3"},
+		},
+		{
+			name: "transactions without nonce keep their position",
+			txs:  []testTx{tx("a2", "alice", 2, 1), tx("x", "alice", 0, 2), tx("a1", "alice", 1, 3)},
+			want: []string{"a1", "x", "a2"},
+		},
+		{
+			name: "max",
+			txs:  []testTx{tx("a2", "alice", 2, 1), tx("a1", "alice", 1, 2), tx("b1", "bob", 1, 3)},
+			max:  2,
+			want: []string{"a1", "a2"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pool := New[testTx](Config{})
+			for _, transaction := range tt.txs {
+				if err := pool.Add(transaction); err != nil {
+					t.Fatalf("Add(%s): %v", transaction.id, err)
+				}
+			}
+			if got := ids(pool.Select(tt.max)); !equalIDs(got, tt.want) {
+				t.Errorf("Select(%d) = %v, want %v", tt.max, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestOrderByNonce(t *testing.T) {
+	txs := []testTx{
+		tx("a3", "alice", 3, 0),
+		tx("b2", "bob", 2, 0),
+		tx("a1", "alice", 1, 0),
+		tx("c1", "carol", 1, 0),
+		tx("b1", "bob", 1, 0),
+		tx("a2", "alice", 2, 0),
+	}
+	orderByNonce(txs)
+	want := []string{"a1", "b1", "a2", "c1", "b2", "a3"}
+	if got := ids(txs); !equalIDs(got, want) {
+		t.Errorf("orderByNonce = %v, want %v", got, want)
+	}
+}
+
+func TestRemoveThroughNonce(t *testing.T) {
+	tests := []struct {
+		name   string
+		sender string
+		nonce  uint64
+		want   []string // 剩余交易，按 Select 顺序
+	}{
+		{"middle", "alice", 2, []string{"b1", "a3", "x"}},
+		{"all", "alice", 3, []string{"b1", "x"}},
+		{"none", "alice", 0, []string{"a1", "b1", "a2", "a3", "x"}},
+		{"other sender", "bob", 5, []string{"a1", "a2", "a3", "x"}},
+		{"unknown sender", "carol", 5, []string{"a1", "b1", "a2", "a3", "x"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pool := New[testTx](Config{})
+			for _, transaction := range []testTx{
+				tx("a1", "alice", 1, 1),
+				tx("b1", "bob", 1, 2),
+				tx("a2", "alice", 2, 3),
+				tx("a3", "alice", 3, 4),
+				tx("x", "alice", 0, 5),
+			} {
+				if err := pool.Add(transaction); err != nil {
+					t.Fatalf("Add(%s): %v", transaction.id, err)
+				}
+			}
+
+			pool.RemoveThroughNonce(tt.sender, tt.nonce)
+			if got := ids(pool.Select(0)); !equalIDs(got, tt.want) {
+				t.Errorf("after RemoveThroughNonce(%s, %d) = %v, want %v", tt.sender, tt.nonce, got, tt.want)
+			}
+			// 被移除的序号可以重新进入交易池
+			if tt.sender == "alice" && tt.nonce > 0 {
+				if err := pool.Add(tx("again", "alice", tt.nonce, 6)); err != nil {
+					t.Errorf("Add nonce %d after removal = %v, want nil", tt.nonce, err)
+				}
+			}
+		})
+	}
+}
@@ -2,14 +2,16 @@ package network
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
+	"strconv"
 	"strings"
 	"time"
 
 	"twichain/internal/blockchain"
-	"twichain/internal/crypto"
+	"twichain/internal/mempool"
 )
 
 type Server struct {
@@ -26,10 +28,25 @@ func NewServer(bc *blockchain.Blockchain, port string) *Server {
 
 	mux := http.NewServeMux()
 	mux.HandleFunc("/transactions/new", s.handleNewTransaction)
+	mux.HandleFunc("/transactions/receive", s.handleReceiveTransaction)
+	mux.HandleFunc("/transactions/proof", s.handleTransactionProof)
 	mux.HandleFunc("/chain", s.handleGetChain)
 	mux.HandleFunc("/nodes/register", s.handleRegisterNodes)
 	mux.HandleFunc("/block/receive", s.handleReceiveBlock)
 	mux.HandleFunc("/nodes/new", s.handleNewNode)
+	mux.HandleFunc("/nodes/resolve", s.handleResolveConflicts)
+	mux.HandleFunc("/mining/status", s.handleMiningStatus)
+	mux.HandleFunc("/accounts/nonce", s.handleAccountNonce)
+	mux.HandleFunc("/posts/likes", s.handlePostLikes)
+	mux.HandleFunc("/posts/history", s.handlePostHistory)
+	mux.HandleFunc("/posts/{id}", s.handleThread)
+	mux.HandleFunc("/users/{address}", s.handleGetUser)
+	mux.HandleFunc("/feed/{address}", s.handleFeed)
+	mux.HandleFunc("/spaces/{space}/posts", s.handleSpacePosts)
+	mux.HandleFunc("/search", s.handleSearch)
+	mux.HandleFunc("/users/{address}/followers", s.handleFollows(blockchain.FollowersRelation))
+	mux.HandleFunc("/users/{address}/following", s.handleFollows(blockchain.FollowingRelation))
+	mux.HandleFunc("/users/{address}/mutuals", s.handleFollows(blockchain.MutualsRelation))
 
 	server := &http.Server{
 		Addr:           ":" + s.port,
@@ -57,12 +74,16 @@ func (s *Server) handleNewTransaction(w http.ResponseWriter, r *http.Request) {
 	}
 
 	var tx struct {
-		Sender       string `json:"sender"`
-		Receiver     string `json:"receiver"`
-		Message      string `json:"message"`   // 原始消息
-		Signature    string `json:"signature"` // EdDSA签名
-		IsLike       bool   `json:"is_like"`
-		TargetPostID string `json:"target_post_id"`
+		Sender       string    `json:"sender"`
+		Receiver     string    `json:"receiver"`
+		Message      string    `json:"message"`   // 原始消息
+		Signature    string    `json:"signature"` // EdDSA签名，覆盖完整交易信封
+		IsLike       bool      `json:"is_like"`
+		TargetPostID string    `json:"target_post_id"`
+		Timestamp    time.Time `json:"timestamp"` // 客户端签名时间
+		Nonce        uint64    `json:"nonce"`
+		Version      int       `json:"version"` // 签名格式版本
+		Kind         string    `json:"kind"`    // 交易类型，为空时由 is_like 和 target_post_id 推导
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&tx); err != nil {
@@ -70,78 +91,92 @@ func (s *Server) handleNewTransaction(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// 验证地址格式
-	if !crypto.ValidateAddress(tx.Sender) || !crypto.ValidateAddress(tx.Receiver) {
-		http.Error(w, "Invalid address format - must be 256-bit hex string", http.StatusBadRequest)
+	// 地址格式、内容和签名由区块链统一验证，通过后加入交易池并广播给其他节点
+	id, index, err := s.blockchain.NewTransaction(blockchain.Transaction{
+		Sender:       tx.Sender,
+		Receiver:     tx.Receiver,
+		Signature:    tx.Signature,
+		IsLike:       tx.IsLike,
+		Timestamp:    tx.Timestamp,
+		Message:      tx.Message,
+		TargetPostID: tx.TargetPostID,
+		Version:      tx.Version,
+		Nonce:        tx.Nonce,
+		Kind:         blockchain.Kind(tx.Kind),
+	})
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Transaction rejected: %v", err), mempoolErrorStatus(err))
 		return
 	}
 
-	// 如果不是点赞，则必须有消息内容
-	if !tx.IsLike && tx.Message == "" {
-		http.Error(w, "Message required for non-like transactions", http.StatusBadRequest)
+	response := map[string]interface{}{
+		"message": fmt.Sprintf("Transaction will be added to Block %d", index),
+		"index":   index,
+		"id":      id,
+	}
+
+	json.NewEncoder(w).Encode(response)
+}
+
+func (s *Server) handleReceiveTransaction(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
 
-	// 添加点赞验证逻辑
-	if tx.IsLike {
-		if tx.TargetPostID == "" {
-			http.Error(w, "Target post ID is required for likes", http.StatusBadRequest)
-			return
-		}
-		// TODO: 验证目标帖子是否存在
+	var tx blockchain.Transaction
+	if err := json.NewDecoder(r.Body).Decode(&tx); err != nil {
+		http.Error(w, "Invalid transaction data", http.StatusBadRequest)
+		return
 	}
 
-	// 验证签名
-	var messageBytes []byte
-	if tx.IsLike {
-		// 对于点赞交易，使用 targetPostID 作为签名内容
-		if tx.TargetPostID == "" {
-			http.Error(w, "Target post ID is required for likes", http.StatusBadRequest)
-			return
-		}
-		messageBytes = []byte(tx.TargetPostID)
-	} else {
-		// 对于其他类型的交易，使用消息内容
-		if tx.Message == "" {
-			http.Error(w, "Message is required for non-like transactions", http.StatusBadRequest)
+	if err := s.blockchain.ReceiveTransaction(tx); err != nil {
+		// 已经处理过的交易不是错误，直接确认即可
+		if errors.Is(err, blockchain.ErrTransactionSeen) {
+			w.WriteHeader(http.StatusOK)
 			return
 		}
-		messageBytes = []byte(tx.Message)
+		http.Error(w, fmt.Sprintf("Transaction rejected: %v", err), mempoolErrorStatus(err))
+		return
 	}
 
-	// 验证签名
-	valid, err := crypto.Verify(tx.Sender, messageBytes, tx.Signature)
-	// log.Printf("Signature verification: %v, err: %v", valid, err)
-	if err != nil {
-		http.Error(w, fmt.Sprintf("Signature verification error: %v", err), http.StatusBadRequest)
+	w.WriteHeader(http.StatusAccepted)
+}
+
+func (s *Server) handleTransactionProof(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
-	if !valid {
-		http.Error(w, "Invalid signature", http.StatusBadRequest)
+
+	txID := r.URL.Query().Get("id")
+	if txID == "" {
+		http.Error(w, "Transaction ID is required", http.StatusBadRequest)
 		return
 	}
 
-	// 处理交易前广播并等待确认
-	index := s.blockchain.NewTransaction(
-		tx.Sender,
-		tx.Receiver,
-		tx.Signature, // 保存签名作为 content
-		tx.IsLike,
-		tx.Message, // 添加原始消息
-		tx.TargetPostID,
-	)
-
-	if index == 0 {
-		http.Error(w, "Transaction failed to get consensus", http.StatusBadRequest)
+	proof, err := s.blockchain.GetTransactionProof(txID)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to build proof: %v", err), http.StatusNotFound)
 		return
 	}
 
-	response := map[string]interface{}{
-		"message": fmt.Sprintf("Transaction will be added to Block %d", index),
-		"index":   index,
-	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(proof)
+}
 
-	json.NewEncoder(w).Encode(response)
+// mempoolErrorStatus 将交易池拒绝原因映射为 HTTP 状态码
+func mempoolErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, mempool.ErrDuplicate), errors.Is(err, mempool.ErrNonceUsed), errors.Is(err, mempool.ErrNonceGap):
+		return http.StatusConflict
+	case errors.Is(err, mempool.ErrSenderQuota):
+		return http.StatusTooManyRequests
+	case errors.Is(err, mempool.ErrPoolFull):
+		return http.StatusServiceUnavailable
+	default:
+		return http.StatusBadRequest
+	}
 }
 
 func (s *Server) handleGetChain(w http.ResponseWriter, r *http.Request) {
@@ -154,8 +189,9 @@ func (s *Server) handleGetChain(w http.ResponseWriter, r *http.Request) {
 	length := s.blockchain.GetChainLength()
 
 	response := map[string]interface{}{
-		"chain":  chain,
-		"length": length,
+		"chain":    chain,
+		"length":   length,
+		"chain_id": s.blockchain.ChainID(),
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -222,6 +258,311 @@ func (s *Server) handleNewNode(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusOK)
 }
 
+func (s *Server) handleResolveConflicts(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	replaced, err := s.blockchain.ResolveConflicts()
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to resolve conflicts: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	message := "Our chain is authoritative"
+	if replaced {
+		message = "Our chain was replaced"
+	}
+
+	response := map[string]interface{}{
+		"message":  message,
+		"replaced": replaced,
+		"chain":    s.blockchain.GetChain(),
+		"length":   s.blockchain.GetChainLength(),
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+func (s *Server) handleMiningStatus(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(s.blockchain.GetMiningStatus())
+}
+
+func (s *Server) handleAccountNonce(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	nonce, err := s.blockchain.GetAccountNonce(r.URL.Query().Get("address"))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to get account nonce: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(nonce)
+}
+
+func (s *Server) handlePostLikes(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	postID := r.URL.Query().Get("id")
+	if postID == "" {
+		http.Error(w, "Post ID is required", http.StatusBadRequest)
+		return
+	}
+
+	count, err := s.blockchain.GetLikeCount(postID)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to get like count: %v", err), http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(count)
+}
+
+func (s *Server) handlePostHistory(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	postID := r.URL.Query().Get("id")
+	if postID == "" {
+		http.Error(w, "Post ID is required", http.StatusBadRequest)
+		return
+	}
+
+	history, err := s.blockchain.GetPostHistory(postID)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to get post history: %v", err), http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(history)
+}
+
+func (s *Server) handleThread(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	limit, offset, err := parsePage(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	var depth int
+	if value := r.URL.Query().Get("depth"); value != "" {
+		if depth, err = strconv.Atoi(value); err != nil {
+			http.Error(w, fmt.Sprintf("invalid depth: %v", err), http.StatusBadRequest)
+			return
+		}
+	}
+
+	thread, err := s.blockchain.GetThread(r.PathValue("id"), depth, limit, offset)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to get thread: %v", err), http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(thread)
+}
+
+func (s *Server) handleGetUser(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	user, err := s.blockchain.GetUser(r.PathValue("address"))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to get user: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(user)
+}
+
+func (s *Server) handleFeed(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	limit, _, err := parsePage(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	feed, err := s.blockchain.GetFeed(r.PathValue("address"), r.URL.Query().Get("cursor"), limit)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to get feed: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(feed)
+}
+
+func (s *Server) handleSpacePosts(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	limit, offset, err := parsePage(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	since, until, err := parseTimeRange(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	page, err := s.blockchain.GetSpacePosts(r.PathValue("space"), blockchain.SpaceFilter{
+		Sort:   blockchain.SpaceSort(r.URL.Query().Get("sort")),
+		Since:  since,
+		Until:  until,
+		Limit:  limit,
+		Offset: offset,
+	})
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to get space posts: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(page)
+}
+
+func (s *Server) handleSearch(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	limit, offset, err := parsePage(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	since, until, err := parseTimeRange(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	query := r.URL.Query()
+	page, err := s.blockchain.Search(blockchain.SearchFilter{
+		Query:  query.Get("q"),
+		Author: query.Get("author"),
+		Since:  since,
+		Until:  until,
+		Sort:   blockchain.SearchSort(query.Get("sort")),
+		Limit:  limit,
+		Offset: offset,
+	})
+	if errors.Is(err, blockchain.ErrSearchUnavailable) {
+		http.Error(w, err.Error(), http.StatusNotImplemented)
+		return
+	}
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to search: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(page)
+}
+
+// parseTimeRange 解析时间范围参数：since 和 until 为 RFC 3339 时间，
+// window 为相对当前时间的时长（如 24h），不能与 since 同时使用；未指定时为零值
+func parseTimeRange(r *http.Request) (since, until time.Time, err error) {
+	query := r.URL.Query()
+	if value := query.Get("since"); value != "" {
+		if since, err = time.Parse(time.RFC3339, value); err != nil {
+			return since, until, fmt.Errorf("invalid since: %v", err)
+		}
+	}
+	if value := query.Get("until"); value != "" {
+		if until, err = time.Parse(time.RFC3339, value); err != nil {
+			return since, until, fmt.Errorf("invalid until: %v", err)
+		}
+	}
+	if value := query.Get("window"); value != "" {
+		if !since.IsZero() {
+			return since, until, fmt.Errorf("window and since cannot be used together")
+		}
+		window, err := time.ParseDuration(value)
+		if err != nil || window <= 0 {
+			return since, until, fmt.Errorf("invalid window %q: must be a positive duration such as 24h", value)
+		}
+		since = time.Now().Add(-window)
+	}
+	return since, until, nil
+}
+
+func (s *Server) handleFollows(relation blockchain.FollowRelation) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		limit, offset, err := parsePage(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		page, err := s.blockchain.GetFollows(r.PathValue("address"), relation, limit, offset)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Failed to get %s: %v", relation, err), http.StatusBadRequest)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(page)
+	}
+}
+
+// parsePage 解析分页参数 limit 和 offset，未指定时为 0
+func parsePage(r *http.Request) (limit, offset int, err error) {
+	query := r.URL.Query()
+	if value := query.Get("limit"); value != "" {
+		if limit, err = strconv.Atoi(value); err != nil {
+			return 0, 0, fmt.Errorf("invalid limit: %v", err)
+		}
+	}
+	if value := query.Get("offset"); value != "" {
+		if offset, err = strconv.Atoi(value); err != nil {
+			return 0, 0, fmt.Errorf("invalid offset: %v", err)
+		}
+	}
+	return limit, offset, nil
+}
+
 func (s *Server) handleReceiveBlock(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
@@ -52,7 +52,12 @@ func createTables(db *sql.DB) error {
             timestamp DATETIME,
             proof INTEGER,
             previous_hash TEXT,
-            transactions TEXT
+            transactions TEXT,
+            difficulty INTEGER NOT NULL DEFAULT 0,
+            merkle_root TEXT NOT NULL DEFAULT '',
+            version INTEGER NOT NULL DEFAULT 0,
+            validator TEXT NOT NULL DEFAULT '',
+            seal TEXT NOT NULL DEFAULT ''
         )
     `)
 	if err != nil {
@@ -71,6 +76,10 @@ func createTables(db *sql.DB) error {
             timestamp DATETIME,
             target_post_id TEXT, -- 目标帖子ID
             block_index INTEGER,
+            version INTEGER NOT NULL DEFAULT 0, -- 签名格式版本
+            nonce INTEGER NOT NULL DEFAULT 0,   -- 发送者序号
+            kind TEXT NOT NULL DEFAULT '',      -- 交易类型
+            tx_index INTEGER NOT NULL DEFAULT 0, -- 在区块中的位置
             FOREIGN KEY(block_index) REFERENCES blocks("index")
         )
     `)
@@ -78,6 +87,18 @@ func createTables(db *sql.DB) error {
 		return err
 	}
 
+	// 创建索引状态表，记录需要跨构建版本校验的索引已经同步到的区块
+	_, err = db.Exec(`
+        CREATE TABLE IF NOT EXISTS index_state (
+            name TEXT PRIMARY KEY,
+            version INTEGER NOT NULL,
+            block_index INTEGER NOT NULL
+        )
+    `)
+	if err != nil {
+		return err
+	}
+
 	// 创建节点表
 	_, err = db.Exec(`
         CREATE TABLE IF NOT EXISTS nodes (
@@ -85,7 +106,104 @@ func createTables(db *sql.DB) error {
             created_at DATETIME DEFAULT CURRENT_TIMESTAMP
         )
     `)
-	return err
+	if err != nil {
+		return err
+	}
+
+	if err := migrateTables(db); err != nil {
+		return err
+	}
+
+	// 交易表的查询索引，需在迁移补充列之后创建
+	for _, statement := range transactionIndexes {
+		if _, err := db.Exec(statement); err != nil {
+			return fmt.Errorf("failed to create transaction index: %v", err)
+		}
+	}
+
+	if err := createIndexes(db); err != nil {
+		return err
+	}
+
+	return syncSearchIndex(db)
+}
+
+// transactionIndexes 交易表上的查询索引
+var transactionIndexes = []string{
+	`CREATE INDEX IF NOT EXISTS idx_transactions_sender_kind ON transactions (sender, kind, block_index, tx_index)`,
+	`CREATE INDEX IF NOT EXISTS idx_transactions_receiver_kind ON transactions (receiver, kind, block_index, tx_index)`,
+	`CREATE INDEX IF NOT EXISTS idx_transactions_target ON transactions (target_post_id, kind, block_index, tx_index)`,
+}
+
+// legacyKindSQL 旧交易没有显式类型，按 is_like 和 target_post_id 推导，与 transactionKind 一致
+const legacyKindSQL = `CASE WHEN is_like THEN 'like' WHEN target_post_id != '' THEN 'comment' ELSE 'post' END`
+
+// migrateTables 为旧版本创建的数据库补充后续新增的列，backfill 在新增列后回填已有数据
+func migrateTables(db *sql.DB) error {
+	migrations := []struct {
+		table      string
+		column     string
+		definition string
+		backfill   string
+	}{
+		{"blocks", "difficulty", "INTEGER NOT NULL DEFAULT 0", ""},
+		{"blocks", "merkle_root", "TEXT NOT NULL DEFAULT ''", ""},
+		{"blocks", "version", "INTEGER NOT NULL DEFAULT 0", ""},
+		{"blocks", "validator", "TEXT NOT NULL DEFAULT ''", ""},
+		{"blocks", "seal", "TEXT NOT NULL DEFAULT ''", ""},
+		{"transactions", "version", "INTEGER NOT NULL DEFAULT 0", ""},
+		{"transactions", "nonce", "INTEGER NOT NULL DEFAULT 0", ""},
+		{"transactions", "kind", "TEXT NOT NULL DEFAULT ''", `UPDATE transactions SET kind = ` + legacyKindSQL + ` WHERE kind = ''`},
+		{"transactions", "tx_index", "INTEGER NOT NULL DEFAULT 0", ""},
+	}
+
+	for _, m := range migrations {
+		exists, err := columnExists(db, m.table, m.column)
+		if err != nil {
+			return err
+		}
+		if exists {
+			continue
+		}
+		if _, err := db.Exec(fmt.Sprintf(`ALTER TABLE %s ADD COLUMN %s %s`, m.table, m.column, m.definition)); err != nil {
+			return fmt.Errorf("failed to add column %s.%s: %v", m.table, m.column, err)
+		}
+		if m.backfill != "" {
+			if _, err := db.Exec(m.backfill); err != nil {
+				return fmt.Errorf("failed to backfill column %s.%s: %v", m.table, m.column, err)
+			}
+		}
+		log.Printf("Migrated table %s: added column %s", m.table, m.column)
+	}
+
+	return nil
+}
+
+// columnExists 检查表中是否存在指定列
+func columnExists(db *sql.DB, table, column string) (bool, error) {
+	rows, err := db.Query(fmt.Sprintf(`PRAGMA table_info(%s)`, table))
+	if err != nil {
+		return false, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			cid        int
+			name       string
+			columnType string
+			notNull    int
+			defaultVal sql.NullString
+			primaryKey int
+		)
+		if err := rows.Scan(&cid, &name, &columnType, &notNull, &defaultVal, &primaryKey); err != nil {
+			return false, err
+		}
+		if name == column {
+			return true, nil
+		}
+	}
+	return false, rows.Err()
 }
 
 func (db *Database) Close() error {
@@ -100,6 +218,53 @@ func (db *Database) SaveBlock(block *BlockData) error {
 	}
 	defer tx.Rollback()
 
+	if err := insertBlock(tx, block); err != nil {
+		return err
+	}
+	if err := indexBlock(tx, block); err != nil {
+		return err
+	}
+	if err := indexSearchBlock(tx, block); err != nil {
+		return fmt.Errorf("failed to update search index: %v", err)
+	}
+
+	return tx.Commit()
+}
+
+// ReplaceBlocksFrom 删除索引不小于 fromIndex 的区块及其交易，并写入新的区块（用于分叉切换）
+func (db *Database) ReplaceBlocksFrom(fromIndex int, blocks []*BlockData) error {
+	tx, err := db.connection.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`DELETE FROM transactions WHERE block_index >= ?`, fromIndex); err != nil {
+		return fmt.Errorf("failed to delete transactions: %v", err)
+	}
+	if _, err := tx.Exec(`DELETE FROM blocks WHERE "index" >= ?`, fromIndex); err != nil {
+		return fmt.Errorf("failed to delete blocks: %v", err)
+	}
+	for _, block := range blocks {
+		if block.Index < fromIndex {
+			return fmt.Errorf("block %d is before fork point %d", block.Index, fromIndex)
+		}
+		if err := insertBlock(tx, block); err != nil {
+			return fmt.Errorf("failed to insert block %d: %v", block.Index, err)
+		}
+	}
+
+	// 被丢弃的区块已经更新过索引，新区块不做增量索引，统一从剩余交易重建，
+	// 避免增量写入与被丢弃交易残留的索引行冲突
+	if err := rebuildIndexes(tx); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// insertBlock 在事务中写入区块及其交易记录，不更新派生索引
+func insertBlock(tx *sql.Tx, block *BlockData) error {
 	// 序列化交易数据
 	transactionsJSON, err := json.Marshal(block.Transactions)
 	if err != nil {
@@ -108,34 +273,98 @@ func (db *Database) SaveBlock(block *BlockData) error {
 
 	// 插入区块
 	_, err = tx.Exec(`
-        INSERT INTO blocks ("index", timestamp, proof, previous_hash, transactions)
-        VALUES (?, ?, ?, ?, ?)
-    `, block.Index, block.Timestamp, block.Proof, block.PrevHash, string(transactionsJSON))
+        INSERT INTO blocks (
+            "index", timestamp, proof, previous_hash, transactions, difficulty, merkle_root, version, validator, seal
+        ) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+    `, block.Index, block.Timestamp, block.Proof, block.PrevHash, string(transactionsJSON),
+		block.Difficulty, block.MerkleRoot, block.Version, block.Validator, block.Seal)
 	if err != nil {
 		return err
 	}
 
 	// 插入交易记录
-	for _, transaction := range block.Transactions {
+	for position, transaction := range block.Transactions {
 		_, err = tx.Exec(`
             INSERT INTO transactions (
-                id, sender, receiver, signature, message, is_like, timestamp, target_post_id, block_index
-            ) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+                id, sender, receiver, signature, message, is_like, timestamp, target_post_id, block_index,
+                version, nonce, kind, tx_index
+            ) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
         `, transaction.ID, transaction.Sender, transaction.Receiver, transaction.Signature,
 			transaction.Message, transaction.IsLike, transaction.Timestamp,
-			transaction.TargetPostID, block.Index)
+			transaction.TargetPostID, block.Index, transaction.Version, int64(transaction.Nonce),
+			transactionKind(transaction), position)
 		if err != nil {
 			return err
 		}
 	}
 
-	return tx.Commit()
+	return nil
+}
+
+// indexBlock 在区块写入交易表之后增量更新派生索引
+func indexBlock(tx *sql.Tx, block *BlockData) error {
+	for position, transaction := range block.Transactions {
+		if err := indexTransaction(tx, block.Index, position, transaction); err != nil {
+			return fmt.Errorf("failed to index transaction %s: %v", transaction.ID, err)
+		}
+	}
+	return nil
+}
+
+// transactionKind 返回交易类型，旧交易按 is_like 和 target_post_id 推导，与 legacyKindSQL 一致
+func transactionKind(transaction TransactionData) string {
+	switch {
+	case transaction.Kind != "":
+		return transaction.Kind
+	case transaction.IsLike:
+		return "like"
+	case transaction.TargetPostID != "":
+		return "comment"
+	default:
+		return "post"
+	}
+}
+
+// blockColumns 读取区块时查询的列，顺序与 scanBlock 一致
+const blockColumns = `"index", timestamp, proof, previous_hash, transactions, difficulty, merkle_root, version, validator, seal`
+
+// rowScanner 兼容 *sql.Row 和 *sql.Rows
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanBlock 读取一行区块数据并反序列化交易
+func scanBlock(row rowScanner) (*BlockData, error) {
+	var block BlockData
+	var transactionsJSON string
+	err := row.Scan(
+		&block.Index,
+		&block.Timestamp,
+		&block.Proof,
+		&block.PrevHash,
+		&transactionsJSON,
+		&block.Difficulty,
+		&block.MerkleRoot,
+		&block.Version,
+		&block.Validator,
+		&block.Seal,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	// 反序列化交易数据
+	if err := json.Unmarshal([]byte(transactionsJSON), &block.Transactions); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal transactions: %v", err)
+	}
+
+	return &block, nil
 }
 
 // GetAllBlocks 修改为返回 BlockData
 func (db *Database) GetAllBlocks() ([]*BlockData, error) {
 	rows, err := db.connection.Query(`
-        SELECT "index", timestamp, proof, previous_hash, transactions 
+        SELECT ` + blockColumns + `
         FROM blocks 
         ORDER BY "index"
     `)
@@ -146,89 +375,44 @@ func (db *Database) GetAllBlocks() ([]*BlockData, error) {
 
 	var blocks []*BlockData
 	for rows.Next() {
-		var block BlockData
-		var transactionsJSON string
-		err := rows.Scan(
-			&block.Index,
-			&block.Timestamp,
-			&block.Proof,
-			&block.PrevHash,
-			&transactionsJSON,
-		)
+		block, err := scanBlock(rows)
 		if err != nil {
 			return nil, err
 		}
-
-		// 反序列化交易数据
-		if err := json.Unmarshal([]byte(transactionsJSON), &block.Transactions); err != nil {
-			return nil, err
-		}
-
-		blocks = append(blocks, &block)
+		blocks = append(blocks, block)
 	}
 
-	return blocks, nil
+	return blocks, rows.Err()
 }
 
 // 添加新的方法实现
 func (db *Database) GetBlockByIndex(index int) (*BlockData, error) {
-	var block BlockData
-	var transactionsJSON string
-
-	err := db.connection.QueryRow(`
-        SELECT "index", timestamp, proof, previous_hash, transactions 
+	row := db.connection.QueryRow(`
+        SELECT `+blockColumns+`
         FROM blocks 
         WHERE "index" = ?
-    `, index).Scan(
-		&block.Index,
-		&block.Timestamp,
-		&block.Proof,
-		&block.PrevHash,
-		&transactionsJSON,
-	)
+    `, index)
 
-	if err != nil {
-		return nil, err
-	}
-
-	// 反序列化交易数据
-	if err := json.Unmarshal([]byte(transactionsJSON), &block.Transactions); err != nil {
-		return nil, err
-	}
-
-	return &block, nil
+	return scanBlock(row)
 }
 
 func (db *Database) GetBlockByHash(hash string) (*BlockData, error) {
-	var block BlockData
-	var transactionsJSON string
-
-	err := db.connection.QueryRow(`
-        SELECT "index", timestamp, proof, previous_hash, transactions 
+	row := db.connection.QueryRow(`
+        SELECT `+blockColumns+`
         FROM blocks 
         WHERE previous_hash = ?
-    `, hash).Scan(
-		&block.Index,
-		&block.Timestamp,
-		&block.Proof,
-		&block.PrevHash,
-		&transactionsJSON,
-	)
+    `, hash)
 
+	block, err := scanBlock(row)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get block by hash: %v", err)
 	}
-
-	if err := json.Unmarshal([]byte(transactionsJSON), &block.Transactions); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal transactions: %v", err)
-	}
-
-	return &block, nil
+	return block, nil
 }
 
 func (db *Database) GetTransactionsByBlockIndex(blockIndex int) ([]TransactionData, error) {
 	rows, err := db.connection.Query(`
-        SELECT id, sender, receiver, signature, is_like, timestamp, message, target_post_id
+        SELECT id, sender, receiver, signature, is_like, timestamp, message, target_post_id, version, nonce, kind
         FROM transactions 
         WHERE block_index = ?
         ORDER BY timestamp
@@ -251,6 +435,9 @@ func (db *Database) GetTransactionsByBlockIndex(blockIndex int) ([]TransactionDa
 			&tx.Timestamp,
 			&tx.Message,
 			&tx.TargetPostID,
+			&tx.Version,
+			&tx.Nonce,
+			&tx.Kind,
 		); err != nil {
 			return nil, err
 		}
@@ -260,6 +447,34 @@ func (db *Database) GetTransactionsByBlockIndex(blockIndex int) ([]TransactionDa
 	return transactions, nil
 }
 
+// GetTransaction 根据 ID 获取已上链的交易及其所在区块索引
+func (db *Database) GetTransaction(id string) (*TransactionData, int, error) {
+	var tx TransactionData
+	var blockIndex int
+	err := db.connection.QueryRow(`
+        SELECT id, sender, receiver, signature, is_like, timestamp, message, target_post_id, version, nonce, kind, block_index
+        FROM transactions
+        WHERE id = ?
+    `, id).Scan(
+		&tx.ID,
+		&tx.Sender,
+		&tx.Receiver,
+		&tx.Signature,
+		&tx.IsLike,
+		&tx.Timestamp,
+		&tx.Message,
+		&tx.TargetPostID,
+		&tx.Version,
+		&tx.Nonce,
+		&tx.Kind,
+		&blockIndex,
+	)
+	if err != nil {
+		return nil, 0, err
+	}
+	return &tx, blockIndex, nil
+}
+
 // 实现节点存储方法
 func (db *Database) SaveNode(address string) error {
 	_, err := db.connection.Exec(`
@@ -0,0 +1,211 @@
+package storage
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func newTestDatabase(t *testing.T) *Database {
+	t.Helper()
+	store, err := NewDatabase(filepath.Join(t.TempDir(), "blockchain.db"))
+	if err != nil {
+		t.Fatalf("NewDatabase: %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+	return store.(*Database)
+}
+
+func testBlock(index int, transactions ...TransactionData) *BlockData {
+	return &BlockData{
+		Index:        index,
+		Timestamp:    time.Unix(int64(index), 0).UTC(),
+		PrevHash:     "prev",
+		Transactions: transactions,
+	}
+}
+
+func testPost(id, sender, message string) TransactionData {
+	return TransactionData{
+		ID:        id,
+		Sender:    sender,
+		Receiver:  "space",
+		Message:   message,
+		Timestamp: time.Unix(1, 0).UTC(),
+		Version:   1,
+		Kind:      "post",
+	}
+}
+
+// search 返回搜索结果，未启用 FTS5 时跳过测试
+func search(t *testing.T, db *Database, terms ...string) []SearchResultData {
+	t.Helper()
+	results, err := db.Search(SearchQuery{Terms: terms, Sort: "relevance", Limit: 10})
+	if errors.Is(err, ErrSearchUnavailable) {
+		t.Skip("built without sqlite_fts5")
+	}
+	if err != nil {
+		t.Fatalf("Search(%q): %v", terms, err)
+	}
+	return results
+}
+
+// searchIDs 返回搜索结果的交易 ID
+func searchIDs(t *testing.T, db *Database, terms ...string) []string {
+	t.Helper()
+	results := search(t, db, terms...)
+	ids := make([]string, len(results))
+	for i, result := range results {
+		ids[i] = result.ID
+	}
+	return ids
+}
+
+func TestReplaceBlocksFromReindexes(t *testing.T) {
+	db := newTestDatabase(t)
+
+	for _, block := range []*BlockData{
+		testBlock(1, testPost("genesis", "system", "genesis post")),
+		testBlock(2, testPost("local", "alice", "local fork content")),
+	} {
+		if err := db.SaveBlock(block); err != nil {
+			t.Fatalf("SaveBlock(%d): %v", block.Index, err)
+		}
+	}
+
+	// 更长的竞争链替换区块 2，重新写入的交易会复用被删除交易的位置
+	err := db.ReplaceBlocksFrom(2, []*BlockData{
+		testBlock(2, testPost("remote-1", "bob", "remote fork content")),
+		testBlock(3, testPost("remote-2", "bob", "more remote content")),
+	})
+	if err != nil {
+		t.Fatalf("ReplaceBlocksFrom: %v", err)
+	}
+
+	blocks, err := db.GetAllBlocks()
+	if err != nil {
+		t.Fatalf("GetAllBlocks: %v", err)
+	}
+	if len(blocks) != 3 {
+		t.Fatalf("got %d blocks after replace, want 3", len(blocks))
+	}
+
+	if ids := searchIDs(t, db, "local"); len(ids) != 0 {
+		t.Errorf("search for dropped content returned %v", ids)
+	}
+	if ids := searchIDs(t, db, "remote"); len(ids) != 2 {
+		t.Errorf("search for new content returned %v, want 2 results", ids)
+	}
+}
+
+func TestSearchIndexRebuiltWhenBehind(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "blockchain.db")
+	store, err := NewDatabase(path)
+	if err != nil {
+		t.Fatalf("NewDatabase: %v", err)
+	}
+	db := store.(*Database)
+	if err := db.SaveBlock(testBlock(1, testPost("genesis", "system", "genesis post"))); err != nil {
+		t.Fatalf("SaveBlock: %v", err)
+	}
+	searchIDs(t, db, "genesis")
+
+	// 模拟未启用 FTS5 的构建写入区块：只写交易表和其他派生索引，并标记全文索引落后
+	tx, err := db.connection.Begin()
+	if err != nil {
+		t.Fatalf("Begin: %v", err)
+	}
+	block := testBlock(2, testPost("offline", "alice", "written without search"))
+	if err := insertBlock(tx, block); err != nil {
+		t.Fatalf("insertBlock: %v", err)
+	}
+	if err := indexBlock(tx, block); err != nil {
+		t.Fatalf("indexBlock: %v", err)
+	}
+	if err := invalidateSearchIndex(tx); err != nil {
+		t.Fatalf("invalidateSearchIndex: %v", err)
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatalf("Commit: %v", err)
+	}
+	store.Close()
+
+	store, err = NewDatabase(path)
+	if err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	defer store.Close()
+	if ids := searchIDs(t, store.(*Database), "without"); len(ids) != 1 || ids[0] != "offline" {
+		t.Errorf("search after reopen returned %v, want [offline]", ids)
+	}
+}
+
+func TestSearchSurvivesVacuum(t *testing.T) {
+	db := newTestDatabase(t)
+	for _, block := range []*BlockData{
+		testBlock(1, testPost("a", "alice", "first apple"), testPost("b", "alice", "second banana")),
+		testBlock(2, testPost("c", "bob", "third cherry")),
+	} {
+		if err := db.SaveBlock(block); err != nil {
+			t.Fatalf("SaveBlock(%d): %v", block.Index, err)
+		}
+	}
+	searchIDs(t, db, "apple")
+
+	// 删除前面的交易后 VACUUM 会重新编号交易表的 rowid
+	if _, err := db.connection.Exec(`DELETE FROM transactions WHERE id IN ('a', 'b')`); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	if _, err := db.connection.Exec(`VACUUM`); err != nil {
+		t.Fatalf("VACUUM: %v", err)
+	}
+	if ids := searchIDs(t, db, "cherry"); len(ids) != 1 || ids[0] != "c" {
+		t.Errorf("search for cherry after VACUUM returned %v, want [c]", ids)
+	}
+}
+
+func TestSearchTerms(t *testing.T) {
+	db := newTestDatabase(t)
+	err := db.SaveBlock(testBlock(1,
+		testPost("weather", "alice", "今天天气很好"),
+		testPost("forecast", "bob", "天气预报说明天下雨"),
+		testPost("pow", "bob", "Proof of Work is slow"),
+	))
+	if err != nil {
+		t.Fatalf("SaveBlock: %v", err)
+	}
+
+	tests := []struct {
+		terms   []string
+		ids     []string
+		snippet string // 第一条结果的片段
+	}{
+		{[]string{"天气"}, []string{"forecast", "weather"}, "<mark>天气</mark>预报说明天下雨"},
+		{[]string{"天气很"}, []string{"weather"}, "今天<mark>天气很</mark>好"},
+		{[]string{"天气", "下雨"}, []string{"forecast"}, "<mark>天气</mark>预报说明天<mark>下雨</mark>"},
+		{[]string{"proof of work"}, []string{"pow"}, "<mark>Proof of Work</mark> is slow"},
+		{[]string{"of", "slow"}, []string{"pow"}, "Pro<mark>of</mark> <mark>of</mark> Work is <mark>slow</mark>"},
+		{[]string{"晴天"}, nil, ""},
+	}
+	for _, tt := range tests {
+		results := search(t, db, tt.terms...)
+		var ids []string
+		for _, result := range results {
+			ids = append(ids, result.ID)
+		}
+		if len(ids) != len(tt.ids) {
+			t.Errorf("Search(%q) = %v, want %v", tt.terms, ids, tt.ids)
+			continue
+		}
+		for i := range ids {
+			if ids[i] != tt.ids[i] {
+				t.Errorf("Search(%q) = %v, want %v", tt.terms, ids, tt.ids)
+				break
+			}
+		}
+		if len(results) > 0 && results[0].Snippet != tt.snippet {
+			t.Errorf("Search(%q) snippet = %q, want %q", tt.terms, results[0].Snippet, tt.snippet)
+		}
+	}
+}
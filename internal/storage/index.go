@@ -0,0 +1,655 @@
+package storage
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+// derivedIndex 由区块数据派生的索引表：写入区块时增量更新，分叉切换时从交易表重建
+type derivedIndex struct {
+	tables  []string // 索引使用的表，第一个表不存在时视为新建并回填
+	schema  []string // 建表语句
+	rebuild []string // 清空后从交易表重建的语句
+}
+
+var derivedIndexes = []derivedIndex{
+	{
+		// 发送者最新确认的交易序号
+		tables: []string{"account_nonces"},
+		schema: []string{`
+            CREATE TABLE IF NOT EXISTS account_nonces (
+                sender TEXT PRIMARY KEY,
+                nonce INTEGER NOT NULL
+            )
+        `},
+		rebuild: []string{`
+            INSERT OR REPLACE INTO account_nonces (sender, nonce)
+            SELECT sender, MAX(nonce) FROM transactions WHERE nonce > 0 GROUP BY sender
+        `},
+	},
+	{
+		// 当前生效的点赞及每个帖子的点赞数
+		tables: []string{"likes", "like_counts"},
+		schema: []string{`
+            CREATE TABLE IF NOT EXISTS likes (
+                sender TEXT NOT NULL,
+                post_id TEXT NOT NULL,
+                PRIMARY KEY (sender, post_id)
+            )
+        `, `
+            CREATE TABLE IF NOT EXISTS like_counts (
+                post_id TEXT PRIMARY KEY,
+                count INTEGER NOT NULL
+            )
+        `},
+		// 每个 (发送者, 帖子) 以最后一笔点赞或取消点赞为准
+		rebuild: []string{`
+            INSERT OR IGNORE INTO likes (sender, post_id)
+            SELECT t.sender, t.target_post_id FROM transactions t
+            WHERE t.kind = 'like' AND NOT EXISTS (
+                SELECT 1 FROM transactions u
+                WHERE u.sender = t.sender AND u.target_post_id = t.target_post_id
+                  AND u.kind IN ('like', 'unlike')
+                  AND (u.block_index > t.block_index OR (u.block_index = t.block_index AND u.tx_index > t.tx_index))
+            )
+        `, `
+            INSERT INTO like_counts (post_id, count)
+            SELECT post_id, COUNT(*) FROM likes GROUP BY post_id
+        `},
+	},
+	{
+		// 帖子的编辑历史和删除墓碑，原始区块数据保持不变
+		tables: []string{"post_edits", "post_tombstones"},
+		schema: []string{`
+            CREATE TABLE IF NOT EXISTS post_edits (
+                edit_id TEXT PRIMARY KEY,
+                post_id TEXT NOT NULL,
+                message TEXT NOT NULL,
+                timestamp DATETIME,
+                block_index INTEGER NOT NULL,
+                tx_index INTEGER NOT NULL
+            )
+        `, `
+            CREATE INDEX IF NOT EXISTS idx_post_edits_post ON post_edits (post_id, block_index, tx_index)
+        `, `
+            CREATE TABLE IF NOT EXISTS post_tombstones (
+                post_id TEXT PRIMARY KEY,
+                delete_id TEXT NOT NULL,
+                timestamp DATETIME,
+                block_index INTEGER NOT NULL
+            )
+        `},
+		rebuild: []string{`
+            INSERT INTO post_edits (edit_id, post_id, message, timestamp, block_index, tx_index)
+            SELECT id, target_post_id, message, timestamp, block_index, tx_index FROM transactions WHERE kind = 'edit'
+        `, `
+            INSERT OR IGNORE INTO post_tombstones (post_id, delete_id, timestamp, block_index)
+            SELECT target_post_id, id, timestamp, block_index FROM transactions WHERE kind = 'delete'
+        `},
+	},
+	{
+		// 当前生效的关注关系及每个账户的粉丝数和关注数
+		tables: []string{"follows", "follow_counts"},
+		schema: []string{`
+            CREATE TABLE IF NOT EXISTS follows (
+                follower TEXT NOT NULL,
+                followee TEXT NOT NULL,
+                block_index INTEGER NOT NULL,
+                tx_index INTEGER NOT NULL,
+                PRIMARY KEY (follower, followee)
+            )
+        `, `
+            CREATE INDEX IF NOT EXISTS idx_follows_followee ON follows (followee, block_index, tx_index)
+        `, `
+            CREATE TABLE IF NOT EXISTS follow_counts (
+                address TEXT PRIMARY KEY,
+                followers INTEGER NOT NULL,
+                following INTEGER NOT NULL
+            )
+        `},
+		// 每个 (关注者, 被关注者) 以最后一笔关注或取消关注为准
+		rebuild: []string{`
+            INSERT OR IGNORE INTO follows (follower, followee, block_index, tx_index)
+            SELECT t.sender, t.receiver, t.block_index, t.tx_index FROM transactions t
+            WHERE t.kind = 'follow' AND NOT EXISTS (
+                SELECT 1 FROM transactions u
+                WHERE u.sender = t.sender AND u.receiver = t.receiver
+                  AND u.kind IN ('follow', 'unfollow')
+                  AND (u.block_index > t.block_index OR (u.block_index = t.block_index AND u.tx_index > t.tx_index))
+            )
+        `, `
+            INSERT INTO follow_counts (address, followers, following)
+            SELECT address, SUM(followers), SUM(following) FROM (
+                SELECT followee AS address, 1 AS followers, 0 AS following FROM follows
+                UNION ALL
+                SELECT follower AS address, 0 AS followers, 1 AS following FROM follows
+            ) GROUP BY address
+        `},
+	},
+	{
+		// 每个账户最后上链的资料交易
+		tables: []string{"profiles"},
+		schema: []string{`
+            CREATE TABLE IF NOT EXISTS profiles (
+                address TEXT PRIMARY KEY,
+                message TEXT NOT NULL,
+                update_id TEXT NOT NULL,
+                timestamp DATETIME,
+                block_index INTEGER NOT NULL,
+                tx_index INTEGER NOT NULL
+            )
+        `},
+		rebuild: []string{`
+            INSERT OR IGNORE INTO profiles (address, message, update_id, timestamp, block_index, tx_index)
+            SELECT t.sender, t.message, t.id, t.timestamp, t.block_index, t.tx_index FROM transactions t
+            WHERE t.kind = 'profile' AND NOT EXISTS (
+                SELECT 1 FROM transactions u
+                WHERE u.sender = t.sender AND u.kind = 'profile'
+                  AND (u.block_index > t.block_index OR (u.block_index = t.block_index AND u.tx_index > t.tx_index))
+            )
+        `},
+	},
+}
+
+// createIndexes 创建派生索引表，新建时从已有交易回填
+func createIndexes(db *sql.DB) error {
+	for _, index := range derivedIndexes {
+		exists, err := tableExists(db, index.tables[0])
+		if err != nil {
+			return err
+		}
+		for _, statement := range index.schema {
+			if _, err := db.Exec(statement); err != nil {
+				return fmt.Errorf("failed to create index table %s: %v", index.tables[0], err)
+			}
+		}
+		if exists {
+			continue
+		}
+		for _, statement := range index.rebuild {
+			if _, err := db.Exec(statement); err != nil {
+				return fmt.Errorf("failed to backfill index table %s: %v", index.tables[0], err)
+			}
+		}
+	}
+	return nil
+}
+
+// rebuildIndexes 在事务中清空并从交易表重建所有派生索引
+func rebuildIndexes(tx *sql.Tx) error {
+	for _, index := range derivedIndexes {
+		for _, table := range index.tables {
+			if _, err := tx.Exec(fmt.Sprintf(`DELETE FROM %s`, table)); err != nil {
+				return fmt.Errorf("failed to clear index table %s: %v", table, err)
+			}
+		}
+		for _, statement := range index.rebuild {
+			if _, err := tx.Exec(statement); err != nil {
+				return fmt.Errorf("failed to rebuild index table %s: %v", index.tables[0], err)
+			}
+		}
+	}
+	// 全文索引依赖上面重建的编辑和墓碑索引
+	return rebuildSearchIndex(tx)
+}
+
+// indexTransaction 写入交易后增量更新派生索引，position 为交易在区块中的位置
+func indexTransaction(tx *sql.Tx, blockIndex, position int, transaction TransactionData) error {
+	if transaction.Nonce > 0 {
+		_, err := tx.Exec(`
+            INSERT INTO account_nonces (sender, nonce) VALUES (?, ?)
+            ON CONFLICT(sender) DO UPDATE SET nonce = MAX(nonce, excluded.nonce)
+        `, transaction.Sender, int64(transaction.Nonce))
+		if err != nil {
+			return err
+		}
+	}
+
+	switch transactionKind(transaction) {
+	case "like":
+		result, err := tx.Exec(`INSERT OR IGNORE INTO likes (sender, post_id) VALUES (?, ?)`,
+			transaction.Sender, transaction.TargetPostID)
+		if err != nil {
+			return err
+		}
+		return adjustLikeCount(tx, result, transaction.TargetPostID, 1)
+	case "unlike":
+		result, err := tx.Exec(`DELETE FROM likes WHERE sender = ? AND post_id = ?`,
+			transaction.Sender, transaction.TargetPostID)
+		if err != nil {
+			return err
+		}
+		return adjustLikeCount(tx, result, transaction.TargetPostID, -1)
+	case "follow":
+		result, err := tx.Exec(`
+            INSERT OR IGNORE INTO follows (follower, followee, block_index, tx_index) VALUES (?, ?, ?, ?)
+        `, transaction.Sender, transaction.Receiver, blockIndex, position)
+		if err != nil {
+			return err
+		}
+		return adjustFollowCounts(tx, result, transaction.Sender, transaction.Receiver, 1)
+	case "unfollow":
+		result, err := tx.Exec(`DELETE FROM follows WHERE follower = ? AND followee = ?`,
+			transaction.Sender, transaction.Receiver)
+		if err != nil {
+			return err
+		}
+		return adjustFollowCounts(tx, result, transaction.Sender, transaction.Receiver, -1)
+	case "profile":
+		_, err := tx.Exec(`
+            INSERT INTO profiles (address, message, update_id, timestamp, block_index, tx_index)
+            VALUES (?, ?, ?, ?, ?, ?)
+            ON CONFLICT(address) DO UPDATE SET
+                message = excluded.message,
+                update_id = excluded.update_id,
+                timestamp = excluded.timestamp,
+                block_index = excluded.block_index,
+                tx_index = excluded.tx_index
+        `, transaction.Sender, transaction.Message, transaction.ID, transaction.Timestamp, blockIndex, position)
+		return err
+	case "edit":
+		_, err := tx.Exec(`
+            INSERT INTO post_edits (edit_id, post_id, message, timestamp, block_index, tx_index)
+            VALUES (?, ?, ?, ?, ?, ?)
+        `, transaction.ID, transaction.TargetPostID, transaction.Message, transaction.Timestamp, blockIndex, position)
+		return err
+	case "delete":
+		_, err := tx.Exec(`
+            INSERT OR IGNORE INTO post_tombstones (post_id, delete_id, timestamp, block_index)
+            VALUES (?, ?, ?, ?)
+        `, transaction.TargetPostID, transaction.ID, transaction.Timestamp, blockIndex)
+		return err
+	}
+	return nil
+}
+
+// adjustLikeCount 点赞状态确实发生变化时调整帖子的点赞数
+func adjustLikeCount(tx *sql.Tx, result sql.Result, postID string, delta int) error {
+	changed, err := result.RowsAffected()
+	if err != nil || changed == 0 {
+		return err
+	}
+	_, err = tx.Exec(`
+        INSERT INTO like_counts (post_id, count) VALUES (?, ?)
+        ON CONFLICT(post_id) DO UPDATE SET count = count + excluded.count
+    `, postID, delta)
+	return err
+}
+
+// adjustFollowCounts 关注关系确实发生变化时调整双方的粉丝数和关注数
+func adjustFollowCounts(tx *sql.Tx, result sql.Result, follower, followee string, delta int) error {
+	changed, err := result.RowsAffected()
+	if err != nil || changed == 0 {
+		return err
+	}
+	_, err = tx.Exec(`
+        INSERT INTO follow_counts (address, followers, following) VALUES (?, ?, 0), (?, 0, ?)
+        ON CONFLICT(address) DO UPDATE SET
+            followers = followers + excluded.followers,
+            following = following + excluded.following
+    `, followee, delta, follower, delta)
+	return err
+}
+
+// tableExists 检查表是否存在
+func tableExists(db *sql.DB, table string) (bool, error) {
+	var count int
+	err := db.QueryRow(`SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = ?`, table).Scan(&count)
+	if err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// GetAccountNonce 返回发送者最新确认的交易序号，没有确认交易时为 0
+func (db *Database) GetAccountNonce(sender string) (uint64, error) {
+	var nonce uint64
+	err := db.connection.QueryRow(`SELECT nonce FROM account_nonces WHERE sender = ?`, sender).Scan(&nonce)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	return nonce, nil
+}
+
+// GetPostEdits 按上链顺序返回帖子的编辑记录
+func (db *Database) GetPostEdits(postID string) ([]PostEditData, error) {
+	rows, err := db.connection.Query(`
+        SELECT edit_id, message, timestamp, block_index
+        FROM post_edits
+        WHERE post_id = ?
+        ORDER BY block_index, tx_index
+    `, postID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var edits []PostEditData
+	for rows.Next() {
+		var edit PostEditData
+		if err := rows.Scan(&edit.EditID, &edit.Message, &edit.Timestamp, &edit.BlockIndex); err != nil {
+			return nil, err
+		}
+		edits = append(edits, edit)
+	}
+	return edits, rows.Err()
+}
+
+// GetTombstone 返回帖子的删除墓碑，帖子未被删除时返回 nil
+func (db *Database) GetTombstone(postID string) (*TombstoneData, error) {
+	var tombstone TombstoneData
+	err := db.connection.QueryRow(`
+        SELECT delete_id, timestamp, block_index FROM post_tombstones WHERE post_id = ?
+    `, postID).Scan(&tombstone.DeleteID, &tombstone.Timestamp, &tombstone.BlockIndex)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &tombstone, nil
+}
+
+// GetLikeCount 返回帖子当前的点赞数
+func (db *Database) GetLikeCount(postID string) (int, error) {
+	var count int
+	err := db.connection.QueryRow(`SELECT count FROM like_counts WHERE post_id = ?`, postID).Scan(&count)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+// GetFollowers 分页返回关注该账户的账户，按关系建立的先后倒序
+func (db *Database) GetFollowers(address string, limit, offset int) ([]FollowData, error) {
+	return db.queryFollows(`
+        SELECT follower, block_index FROM follows
+        WHERE followee = ?
+        ORDER BY block_index DESC, tx_index DESC
+        LIMIT ? OFFSET ?
+    `, address, limit, offset)
+}
+
+// GetFollowing 分页返回该账户关注的账户，按关系建立的先后倒序
+func (db *Database) GetFollowing(address string, limit, offset int) ([]FollowData, error) {
+	return db.queryFollows(`
+        SELECT followee, block_index FROM follows
+        WHERE follower = ?
+        ORDER BY block_index DESC, tx_index DESC
+        LIMIT ? OFFSET ?
+    `, address, limit, offset)
+}
+
+// GetMutuals 分页返回与该账户互相关注的账户，block_index 为较晚建立的一方关系所在的区块
+func (db *Database) GetMutuals(address string, limit, offset int) ([]FollowData, error) {
+	return db.queryFollows(`
+        SELECT f.followee, MAX(f.block_index, b.block_index) AS since FROM follows f
+        JOIN follows b ON b.follower = f.followee AND b.followee = f.follower
+        WHERE f.follower = ?
+        ORDER BY since DESC, f.followee
+        LIMIT ? OFFSET ?
+    `, address, limit, offset)
+}
+
+// queryFollows 执行返回 (地址, 区块索引) 的关注关系查询
+func (db *Database) queryFollows(query string, args ...interface{}) ([]FollowData, error) {
+	rows, err := db.connection.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	follows := []FollowData{}
+	for rows.Next() {
+		var follow FollowData
+		if err := rows.Scan(&follow.Address, &follow.BlockIndex); err != nil {
+			return nil, err
+		}
+		follows = append(follows, follow)
+	}
+	return follows, rows.Err()
+}
+
+// GetFollowCounts 返回账户的粉丝数和关注数
+func (db *Database) GetFollowCounts(address string) (followers, following int, err error) {
+	err = db.connection.QueryRow(`
+        SELECT followers, following FROM follow_counts WHERE address = ?
+    `, address).Scan(&followers, &following)
+	if err == sql.ErrNoRows {
+		return 0, 0, nil
+	}
+	return followers, following, err
+}
+
+// GetProfile 返回账户最后上链的资料交易，没有资料交易时返回 nil
+func (db *Database) GetProfile(address string) (*ProfileData, error) {
+	var profile ProfileData
+	err := db.connection.QueryRow(`
+        SELECT message, update_id, timestamp, block_index FROM profiles WHERE address = ?
+    `, address).Scan(&profile.Message, &profile.UpdateID, &profile.Timestamp, &profile.BlockIndex)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &profile, nil
+}
+
+// GetUserStats 从交易表和派生索引统计账户的帖子、评论、关注和点赞，已删除的帖子和评论不计入
+func (db *Database) GetUserStats(address string) (*UserStatsData, error) {
+	var stats UserStatsData
+	err := db.connection.QueryRow(`
+        SELECT COALESCE(SUM(t.kind = 'post'), 0), COALESCE(SUM(t.kind = 'comment'), 0), COALESCE(SUM(c.count), 0)
+        FROM transactions t
+        LEFT JOIN like_counts c ON c.post_id = t.id
+        WHERE t.sender = ? AND t.kind IN ('post', 'comment')
+          AND NOT EXISTS (SELECT 1 FROM post_tombstones d WHERE d.post_id = t.id)
+    `, address).Scan(&stats.Posts, &stats.Comments, &stats.LikesReceived)
+	if err != nil {
+		return nil, err
+	}
+
+	err = db.connection.QueryRow(`SELECT COUNT(*) FROM likes WHERE sender = ?`, address).Scan(&stats.LikesGiven)
+	if err != nil {
+		return nil, err
+	}
+
+	stats.Followers, stats.Following, err = db.GetFollowCounts(address)
+	if err != nil {
+		return nil, err
+	}
+	return &stats, nil
+}
+
+// GetFeed 返回该账户关注的账户发布的未删除帖子，按上链位置倒序，以 (beforeBlock, beforeTx) 为游标分页
+func (db *Database) GetFeed(address string, beforeBlock, beforeTx, limit int) ([]FeedPostData, error) {
+	rows, err := db.connection.Query(`
+        SELECT t.id, t.sender, t.receiver, t.message, t.timestamp, t.block_index, t.tx_index, e.message
+        FROM follows f
+        JOIN transactions t ON t.sender = f.followee AND t.kind = 'post'
+        LEFT JOIN post_edits e ON e.edit_id = (
+            SELECT edit_id FROM post_edits WHERE post_id = t.id ORDER BY block_index DESC, tx_index DESC LIMIT 1
+        )
+        WHERE f.follower = ?
+          AND (t.block_index < ? OR (t.block_index = ? AND t.tx_index < ?))
+          AND NOT EXISTS (SELECT 1 FROM post_tombstones d WHERE d.post_id = t.id)
+        ORDER BY t.block_index DESC, t.tx_index DESC
+        LIMIT ?
+    `, address, beforeBlock, beforeBlock, beforeTx, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	posts := []FeedPostData{}
+	for rows.Next() {
+		var post FeedPostData
+		var edited sql.NullString
+		if err := rows.Scan(&post.ID, &post.Sender, &post.Receiver, &post.Message, &post.Timestamp,
+			&post.BlockIndex, &post.TxIndex, &edited); err != nil {
+			return nil, err
+		}
+		if edited.Valid {
+			post.Message = edited.String
+			post.Edited = true
+		}
+		posts = append(posts, post)
+	}
+	return posts, rows.Err()
+}
+
+// threadItemColumns 讨论串条目的查询列，t 为交易表中的帖子或评论
+const threadItemColumns = `
+    t.id, t.sender, t.receiver, t.kind, t.target_post_id, t.message, t.timestamp, t.block_index,
+    (SELECT message FROM post_edits WHERE post_id = t.id ORDER BY block_index DESC, tx_index DESC LIMIT 1),
+    EXISTS (SELECT 1 FROM post_tombstones WHERE post_id = t.id),
+    COALESCE((SELECT count FROM like_counts WHERE post_id = t.id), 0),
+    (SELECT COUNT(*) FROM transactions r WHERE r.target_post_id = t.id AND r.kind = 'comment')
+`
+
+// GetThreadItem 返回已上链的帖子或评论，不存在时返回 sql.ErrNoRows
+func (db *Database) GetThreadItem(id string) (*ThreadItemData, error) {
+	row := db.connection.QueryRow(`
+        SELECT `+threadItemColumns+` FROM transactions t
+        WHERE t.id = ? AND t.kind IN ('post', 'comment')
+    `, id)
+	return scanThreadItem(row)
+}
+
+// GetReplies 返回每个父帖子或评论按上链顺序的一页直接回复
+func (db *Database) GetReplies(parentIDs []string, limit, offset int) ([]ThreadItemData, error) {
+	if len(parentIDs) == 0 {
+		return []ThreadItemData{}, nil
+	}
+
+	placeholders := strings.Repeat("?, ", len(parentIDs)-1) + "?"
+	args := make([]interface{}, 0, len(parentIDs)+2)
+	for _, id := range parentIDs {
+		args = append(args, id)
+	}
+	args = append(args, offset, offset+limit)
+
+	// 先按父节点编号分页，再只为选中的评论计算编辑、点赞和回复数
+	rows, err := db.connection.Query(`
+        SELECT `+threadItemColumns+` FROM (
+            SELECT id, ROW_NUMBER() OVER (
+                PARTITION BY target_post_id ORDER BY block_index, tx_index
+            ) AS position
+            FROM transactions
+            WHERE target_post_id IN (`+placeholders+`) AND kind = 'comment'
+        ) page
+        JOIN transactions t ON t.id = page.id
+        WHERE page.position > ? AND page.position <= ?
+        ORDER BY t.block_index, t.tx_index
+    `, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	replies := []ThreadItemData{}
+	for rows.Next() {
+		item, err := scanThreadItem(rows)
+		if err != nil {
+			return nil, err
+		}
+		replies = append(replies, *item)
+	}
+	return replies, rows.Err()
+}
+
+// scanThreadItem 扫描 threadItemColumns 查询的一行，应用最后一次编辑并隐藏已删除的内容
+func scanThreadItem(row interface{ Scan(...interface{}) error }) (*ThreadItemData, error) {
+	var item ThreadItemData
+	var edited sql.NullString
+	if err := row.Scan(&item.ID, &item.Sender, &item.Receiver, &item.Kind, &item.TargetPostID, &item.Message,
+		&item.Timestamp, &item.BlockIndex, &edited, &item.Deleted, &item.Likes, &item.Replies); err != nil {
+		return nil, err
+	}
+	if edited.Valid {
+		item.Message = edited.String
+		item.Edited = true
+	}
+	if item.Deleted {
+		item.Message = ""
+	}
+	return &item, nil
+}
+
+// spacePostOrders 空间帖子列表支持的排序方式，最后都以上链位置倒序保证结果确定
+var spacePostOrders = map[string]string{
+	"recent": `t.block_index DESC, t.tx_index DESC`,
+	"top":    `likes DESC, t.block_index DESC, t.tx_index DESC`,
+	"active": `COALESCE(a.block_index, t.block_index) DESC, COALESCE(a.tx_index, t.tx_index) DESC, t.block_index DESC, t.tx_index DESC`,
+}
+
+// GetSpacePosts 返回发往空间地址的未删除帖子，active 按最新一条直接评论的上链位置排序，没有评论的帖子按自身位置
+// 时间范围按帖子签名的时间戳过滤，用 julianday 比较以正确处理不同时区的时间戳
+func (db *Database) GetSpacePosts(query SpaceQuery) ([]SpacePostData, error) {
+	order, ok := spacePostOrders[query.Sort]
+	if !ok {
+		return nil, fmt.Errorf("unknown sort %q", query.Sort)
+	}
+
+	conditions := []string{`t.receiver = ?`, `t.kind = 'post'`,
+		`NOT EXISTS (SELECT 1 FROM post_tombstones d WHERE d.post_id = t.id)`}
+	args := []interface{}{query.Receiver}
+	if !query.Since.IsZero() {
+		conditions = append(conditions, `julianday(t.timestamp) >= julianday(?)`)
+		args = append(args, query.Since)
+	}
+	if !query.Until.IsZero() {
+		conditions = append(conditions, `julianday(t.timestamp) < julianday(?)`)
+		args = append(args, query.Until)
+	}
+	args = append(args, query.Limit, query.Offset)
+
+	rows, err := db.connection.Query(`
+        SELECT t.id, t.sender, t.message, t.timestamp, t.block_index,
+            (SELECT message FROM post_edits WHERE post_id = t.id ORDER BY block_index DESC, tx_index DESC LIMIT 1),
+            COALESCE(l.count, 0) AS likes,
+            (SELECT COUNT(*) FROM transactions c WHERE c.target_post_id = t.id AND c.kind = 'comment'),
+            a.timestamp
+        FROM transactions t
+        LEFT JOIN like_counts l ON l.post_id = t.id
+        LEFT JOIN transactions a ON a.id = (
+            SELECT id FROM transactions c WHERE c.target_post_id = t.id AND c.kind = 'comment'
+            ORDER BY block_index DESC, tx_index DESC LIMIT 1
+        )
+        WHERE `+strings.Join(conditions, " AND ")+`
+        ORDER BY `+order+`
+        LIMIT ? OFFSET ?
+    `, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	posts := []SpacePostData{}
+	for rows.Next() {
+		var post SpacePostData
+		var edited sql.NullString
+		var lastComment sql.NullTime
+		if err := rows.Scan(&post.ID, &post.Sender, &post.Message, &post.Timestamp, &post.BlockIndex,
+			&edited, &post.Likes, &post.Comments, &lastComment); err != nil {
+			return nil, err
+		}
+		if edited.Valid {
+			post.Message = edited.String
+			post.Edited = true
+		}
+		if lastComment.Valid {
+			post.LastCommentAt = &lastComment.Time
+		}
+		posts = append(posts, post)
+	}
+	return posts, rows.Err()
+}
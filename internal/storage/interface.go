@@ -1,15 +1,24 @@
 package storage
 
 import (
+	"errors"
 	"time"
 )
 
+// ErrSearchUnavailable 未使用 sqlite_fts5 构建标签编译时全文搜索不可用
+var ErrSearchUnavailable = errors.New("full-text search is not available: build with -tags sqlite_fts5")
+
 // BlockData 定义区块数据结构
 type BlockData struct {
 	Index        int               `json:"index"`
 	Timestamp    time.Time         `json:"timestamp"`
 	Proof        int64             `json:"proof"`
 	PrevHash     string            `json:"previous_hash"`
+	Difficulty   int               `json:"difficulty"`
+	MerkleRoot   string            `json:"merkle_root"`
+	Version      int               `json:"version"`
+	Validator    string            `json:"validator"`
+	Seal         string            `json:"seal"`
 	Transactions []TransactionData `json:"transactions"`
 }
 
@@ -23,6 +32,122 @@ type TransactionData struct {
 	Timestamp    time.Time `json:"timestamp"`
 	Message      string    `json:"message"`
 	TargetPostID string    `json:"target_post_id"`
+	Version      int       `json:"version"`
+	Nonce        uint64    `json:"nonce"`
+	Kind         string    `json:"kind,omitempty"`
+}
+
+// PostEditData 帖子的一次编辑
+type PostEditData struct {
+	EditID     string    `json:"edit_id"`
+	Message    string    `json:"message"`
+	Timestamp  time.Time `json:"timestamp"`
+	BlockIndex int       `json:"block_index"`
+}
+
+// TombstoneData 帖子的删除墓碑
+type TombstoneData struct {
+	DeleteID   string    `json:"delete_id"`
+	Timestamp  time.Time `json:"timestamp"`
+	BlockIndex int       `json:"block_index"`
+}
+
+// FollowData 社交关系中的一个账户及关系建立时所在的区块
+type FollowData struct {
+	Address    string `json:"address"`
+	BlockIndex int    `json:"block_index"`
+}
+
+// ProfileData 账户当前生效的资料交易
+type ProfileData struct {
+	Message    string    `json:"message"` // JSON 资料
+	UpdateID   string    `json:"update_id"`
+	Timestamp  time.Time `json:"timestamp"`
+	BlockIndex int       `json:"block_index"`
+}
+
+// UserStatsData 账户的统计
+type UserStatsData struct {
+	Posts         int `json:"posts"`
+	Comments      int `json:"comments"`
+	Followers     int `json:"followers"`
+	Following     int `json:"following"`
+	LikesReceived int `json:"likes_received"`
+	LikesGiven    int `json:"likes_given"`
+}
+
+// FeedPostData 时间线中的一条已上链帖子，Message 为最后一次编辑后的内容
+type FeedPostData struct {
+	ID         string    `json:"id"`
+	Sender     string    `json:"sender"`
+	Receiver   string    `json:"receiver"`
+	Message    string    `json:"message"`
+	Timestamp  time.Time `json:"timestamp"`
+	BlockIndex int       `json:"block_index"`
+	TxIndex    int       `json:"tx_index"`
+	Edited     bool      `json:"edited"`
+}
+
+// ThreadItemData 帖子讨论串中的一个帖子或评论，Message 为最后一次编辑后的内容，已删除时为空
+type ThreadItemData struct {
+	ID           string    `json:"id"`
+	Sender       string    `json:"sender"`
+	Receiver     string    `json:"receiver"`
+	Kind         string    `json:"kind"`
+	TargetPostID string    `json:"target_post_id"`
+	Message      string    `json:"message"`
+	Timestamp    time.Time `json:"timestamp"`
+	BlockIndex   int       `json:"block_index"`
+	Edited       bool      `json:"edited"`
+	Deleted      bool      `json:"deleted"`
+	Likes        int       `json:"likes"`
+	Replies      int       `json:"replies"` // 直接回复的评论数
+}
+
+// SpacePostData 空间中的一条已上链帖子，Message 为最后一次编辑后的内容
+type SpacePostData struct {
+	ID            string     `json:"id"`
+	Sender        string     `json:"sender"`
+	Message       string     `json:"message"`
+	Timestamp     time.Time  `json:"timestamp"`
+	BlockIndex    int        `json:"block_index"`
+	Edited        bool       `json:"edited"`
+	Likes         int        `json:"likes"`
+	Comments      int        `json:"comments"`        // 直接评论数
+	LastCommentAt *time.Time `json:"last_comment_at"` // 最新一条直接评论的时间，没有评论时为 nil
+}
+
+// SpaceQuery 空间帖子列表的查询条件
+type SpaceQuery struct {
+	Receiver string    // 空间地址
+	Sort     string    // recent、top 或 active
+	Since    time.Time // 只返回此时间及之后发布的帖子，零值表示不限制
+	Until    time.Time // 只返回此时间之前发布的帖子，零值表示不限制
+	Limit    int
+	Offset   int
+}
+
+// SearchQuery 全文搜索的查询条件
+type SearchQuery struct {
+	Terms  []string  // 必须全部出现在内容中的词或短语，按子串匹配，忽略 ASCII 大小写
+	Author string    // 只返回该发送者的帖子和评论，为空表示不限制
+	Since  time.Time // 只返回此时间及之后发布的内容，零值表示不限制
+	Until  time.Time // 只返回此时间之前发布的内容，零值表示不限制
+	Sort   string    // relevance（bm25 相关度）或 recent
+	Limit  int
+	Offset int
+}
+
+// SearchResultData 一条搜索结果，Snippet 为用 <mark></mark> 标出匹配词的内容片段
+type SearchResultData struct {
+	ID           string    `json:"id"`
+	Sender       string    `json:"sender"`
+	Kind         string    `json:"kind"`
+	TargetPostID string    `json:"target_post_id,omitempty"`
+	Timestamp    time.Time `json:"timestamp"`
+	BlockIndex   int       `json:"block_index"`
+	Snippet      string    `json:"snippet"`
+	Rank         float64   `json:"rank"` // bm25 分数，越小越相关
 }
 
 // BlockStorage 定义区块链存储接口
@@ -30,6 +155,9 @@ type BlockStorage interface {
 	// SaveBlock 保存区块到存储
 	SaveBlock(block *BlockData) error
 
+	// ReplaceBlocksFrom 删除索引不小于 fromIndex 的区块及交易，并写入新区块
+	ReplaceBlocksFrom(fromIndex int, blocks []*BlockData) error
+
 	// GetAllBlocks 获取所有区块
 	GetAllBlocks() ([]*BlockData, error)
 
@@ -42,6 +170,55 @@ type BlockStorage interface {
 	// GetTransactionsByBlockIndex 获取指定区块的所有交易
 	GetTransactionsByBlockIndex(blockIndex int) ([]TransactionData, error)
 
+	// GetAccountNonce 获取发送者最新确认的交易序号
+	GetAccountNonce(sender string) (uint64, error)
+
+	// GetLikeCount 获取帖子当前的点赞数
+	GetLikeCount(postID string) (int, error)
+
+	// GetTransaction 根据 ID 获取已上链的交易及其所在区块索引
+	GetTransaction(id string) (*TransactionData, int, error)
+
+	// GetPostEdits 按上链顺序获取帖子的编辑记录
+	GetPostEdits(postID string) ([]PostEditData, error)
+
+	// GetTombstone 获取帖子的删除墓碑，未删除时返回 nil
+	GetTombstone(postID string) (*TombstoneData, error)
+
+	// GetFollowers 分页获取关注该账户的账户，最近建立的关系在前
+	GetFollowers(address string, limit, offset int) ([]FollowData, error)
+
+	// GetFollowing 分页获取该账户关注的账户，最近建立的关系在前
+	GetFollowing(address string, limit, offset int) ([]FollowData, error)
+
+	// GetMutuals 分页获取与该账户互相关注的账户
+	GetMutuals(address string, limit, offset int) ([]FollowData, error)
+
+	// GetFollowCounts 获取账户的粉丝数和关注数
+	GetFollowCounts(address string) (followers, following int, err error)
+
+	// GetProfile 获取账户最后上链的资料交易，没有时返回 nil
+	GetProfile(address string) (*ProfileData, error)
+
+	// GetUserStats 获取账户的帖子、评论、关注和点赞统计
+	GetUserStats(address string) (*UserStatsData, error)
+
+	// GetFeed 获取该账户关注的账户发布的未删除帖子，按 (区块索引, 区块内位置) 倒序，
+	// 只返回位置在 (beforeBlock, beforeTx) 之前的帖子
+	GetFeed(address string, beforeBlock, beforeTx, limit int) ([]FeedPostData, error)
+
+	// GetThreadItem 获取已上链的帖子或评论及其点赞数和回复数
+	GetThreadItem(id string) (*ThreadItemData, error)
+
+	// GetReplies 获取每个父帖子或评论按上链顺序的第 offset+1 到 offset+limit 条直接回复
+	GetReplies(parentIDs []string, limit, offset int) ([]ThreadItemData, error)
+
+	// GetSpacePosts 按排序方式和时间范围分页获取发往空间地址的未删除帖子
+	GetSpacePosts(query SpaceQuery) ([]SpacePostData, error)
+
+	// Search 在帖子和评论的当前内容中全文搜索，未启用 FTS5 时返回 ErrSearchUnavailable
+	Search(query SearchQuery) ([]SearchResultData, error)
+
 	// Close 关闭存储连接
 	Close() error
 
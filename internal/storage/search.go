@@ -0,0 +1,16 @@
+package storage
+
+import (
+	"database/sql"
+)
+
+// searchIndexName 全文索引在 index_state 中的名称
+// 全文索引只在 sqlite_fts5 构建中维护：其他构建写入区块时删除这条状态，
+// 之后以 sqlite_fts5 构建启动时发现状态缺失，从交易表重建全文索引
+const searchIndexName = "post_search"
+
+// invalidateSearchIndex 标记全文索引已经落后于交易表
+func invalidateSearchIndex(tx *sql.Tx) error {
+	_, err := tx.Exec(`DELETE FROM index_state WHERE name = ?`, searchIndexName)
+	return err
+}
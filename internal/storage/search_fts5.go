@@ -0,0 +1,287 @@
+//go:build sqlite_fts5
+
+package storage
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// searchIndexVersion 全文索引的表结构版本，变更表结构或分词器时递增，启动时据此重建
+const searchIndexVersion = 2
+
+// searchSchema 全文索引的表结构：post_search_docs 用 INTEGER PRIMARY KEY 为帖子和评论分配稳定的文档编号，
+// post_search 的 rowid 即文档编号，编辑和删除按交易 ID 经 post_search_docs 定位文档
+// 使用 trigram 分词器按字符三元组索引，中文等不以空格分词的内容也能按子串搜索
+var searchSchema = []string{`
+    CREATE TABLE post_search_docs (
+        rowid INTEGER PRIMARY KEY,
+        id TEXT NOT NULL UNIQUE
+    )
+`, `
+    CREATE VIRTUAL TABLE post_search USING fts5(message, tokenize = 'trigram')
+`}
+
+// searchRebuild 从交易表填充全文索引：帖子和评论的当前内容，编辑后为最新内容，删除的不收录
+var searchRebuild = []string{`
+    INSERT INTO post_search_docs (id)
+    SELECT t.id FROM transactions t
+    WHERE t.kind IN ('post', 'comment')
+      AND NOT EXISTS (SELECT 1 FROM post_tombstones d WHERE d.post_id = t.id)
+    ORDER BY t.block_index, t.tx_index
+`, `
+    INSERT INTO post_search (rowid, message)
+    SELECT d.rowid, COALESCE(
+        (SELECT message FROM post_edits WHERE post_id = t.id ORDER BY block_index DESC, tx_index DESC LIMIT 1),
+        t.message
+    )
+    FROM post_search_docs d
+    JOIN transactions t ON t.id = d.id
+`}
+
+// syncSearchIndex 启动时校验全文索引：表结构版本不同，或者没有同步到最新区块
+// （例如数据库曾被未启用 FTS5 的构建写入过）时，删除并从交易表重建
+func syncSearchIndex(db *sql.DB) error {
+	var version, indexed int
+	err := db.QueryRow(`SELECT version, block_index FROM index_state WHERE name = ?`, searchIndexName).
+		Scan(&version, &indexed)
+	if err != nil && err != sql.ErrNoRows {
+		return err
+	}
+	var tip int
+	if err := db.QueryRow(`SELECT COALESCE(MAX("index"), 0) FROM blocks`).Scan(&tip); err != nil {
+		return err
+	}
+	if err == nil && version == searchIndexVersion && indexed == tip {
+		return nil
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, statement := range []string{`DROP TABLE IF EXISTS post_search`, `DROP TABLE IF EXISTS post_search_docs`} {
+		if _, err := tx.Exec(statement); err != nil {
+			return fmt.Errorf("failed to drop search index: %v", err)
+		}
+	}
+	for _, statement := range searchSchema {
+		if _, err := tx.Exec(statement); err != nil {
+			return fmt.Errorf("failed to create search index: %v", err)
+		}
+	}
+	if err := fillSearchIndex(tx); err != nil {
+		return err
+	}
+	if tip > 0 {
+		log.Printf("Rebuilt search index up to block %d", tip)
+	}
+	return tx.Commit()
+}
+
+// rebuildSearchIndex 在事务中清空并从交易表重建全文索引
+func rebuildSearchIndex(tx *sql.Tx) error {
+	for _, table := range []string{"post_search", "post_search_docs"} {
+		if _, err := tx.Exec(fmt.Sprintf(`DELETE FROM %s`, table)); err != nil {
+			return fmt.Errorf("failed to clear search index: %v", err)
+		}
+	}
+	return fillSearchIndex(tx)
+}
+
+// fillSearchIndex 从交易表填充空的全文索引，并记录同步到的区块
+func fillSearchIndex(tx *sql.Tx) error {
+	for _, statement := range searchRebuild {
+		if _, err := tx.Exec(statement); err != nil {
+			return fmt.Errorf("failed to fill search index: %v", err)
+		}
+	}
+	_, err := tx.Exec(`
+        INSERT OR REPLACE INTO index_state (name, version, block_index)
+        SELECT ?, ?, COALESCE(MAX("index"), 0) FROM blocks
+    `, searchIndexName, searchIndexVersion)
+	return err
+}
+
+// indexSearchBlock 在区块写入交易表之后增量更新全文索引，并记录同步到的区块
+func indexSearchBlock(tx *sql.Tx, block *BlockData) error {
+	for _, transaction := range block.Transactions {
+		if err := indexSearch(tx, transaction); err != nil {
+			return err
+		}
+	}
+	_, err := tx.Exec(`
+        UPDATE index_state SET block_index = ? WHERE name = ? AND version = ?
+    `, block.Index, searchIndexName, searchIndexVersion)
+	return err
+}
+
+// indexSearch 按交易类型更新全文索引
+func indexSearch(tx *sql.Tx, transaction TransactionData) error {
+	switch transactionKind(transaction) {
+	case "post", "comment":
+		result, err := tx.Exec(`INSERT INTO post_search_docs (id) VALUES (?)`, transaction.ID)
+		if err != nil {
+			return err
+		}
+		doc, err := result.LastInsertId()
+		if err != nil {
+			return err
+		}
+		_, err = tx.Exec(`INSERT INTO post_search (rowid, message) VALUES (?, ?)`, doc, transaction.Message)
+		return err
+	case "edit":
+		_, err := tx.Exec(`
+            UPDATE post_search SET message = ? WHERE rowid = (SELECT rowid FROM post_search_docs WHERE id = ?)
+        `, transaction.Message, transaction.TargetPostID)
+		return err
+	case "delete":
+		if _, err := tx.Exec(`
+            DELETE FROM post_search WHERE rowid = (SELECT rowid FROM post_search_docs WHERE id = ?)
+        `, transaction.TargetPostID); err != nil {
+			return err
+		}
+		_, err := tx.Exec(`DELETE FROM post_search_docs WHERE id = ?`, transaction.TargetPostID)
+		return err
+	}
+	return nil
+}
+
+// searchOrders 搜索结果支持的排序方式，最后都以上链位置倒序保证结果确定
+var searchOrders = map[string]string{
+	"relevance": `rank, t.block_index DESC, t.tx_index DESC`,
+	"recent":    `t.block_index DESC, t.tx_index DESC`,
+}
+
+// 片段中匹配词前后保留的字符数
+const snippetContext = 16
+
+// Search 用 FTS5 在帖子和评论的当前内容中搜索，按 bm25 相关度或上链位置排序
+// trigram 分词器无法匹配少于 3 个字符的词，这些词改为对索引内容做子串过滤；
+// 查询中没有 3 个字符以上的词时不计算相关度。trigram 下 snippet() 会按三元组截断出残缺的字，片段统一由 markSnippet 生成
+func (db *Database) Search(query SearchQuery) ([]SearchResultData, error) {
+	order, ok := searchOrders[query.Sort]
+	if !ok {
+		return nil, fmt.Errorf("unknown sort %q", query.Sort)
+	}
+	if len(query.Terms) == 0 {
+		return nil, fmt.Errorf("search query is required")
+	}
+
+	var phrases []string
+	var conditions []string
+	var args []interface{}
+	for _, term := range query.Terms {
+		if utf8.RuneCountInString(term) >= 3 {
+			phrases = append(phrases, `"`+strings.ReplaceAll(term, `"`, `""`)+`"`)
+			continue
+		}
+		conditions = append(conditions, `instr(lower(post_search.message), lower(?)) > 0`)
+		args = append(args, term)
+	}
+
+	rank := `0`
+	if len(phrases) > 0 {
+		rank = `bm25(post_search)`
+		conditions = append([]string{`post_search MATCH ?`}, conditions...)
+		args = append([]interface{}{strings.Join(phrases, " AND ")}, args...)
+	}
+	if query.Author != "" {
+		conditions = append(conditions, `t.sender = ?`)
+		args = append(args, query.Author)
+	}
+	if !query.Since.IsZero() {
+		conditions = append(conditions, `julianday(t.timestamp) >= julianday(?)`)
+		args = append(args, query.Since)
+	}
+	if !query.Until.IsZero() {
+		conditions = append(conditions, `julianday(t.timestamp) < julianday(?)`)
+		args = append(args, query.Until)
+	}
+	args = append(args, query.Limit, query.Offset)
+
+	rows, err := db.connection.Query(`
+        SELECT t.id, t.sender, t.kind, t.target_post_id, t.timestamp, t.block_index,
+               post_search.message, `+rank+` AS rank
+        FROM post_search
+        JOIN post_search_docs d ON d.rowid = post_search.rowid
+        JOIN transactions t ON t.id = d.id
+        WHERE `+strings.Join(conditions, " AND ")+`
+        ORDER BY `+order+`
+        LIMIT ? OFFSET ?
+    `, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	results := []SearchResultData{}
+	for rows.Next() {
+		var result SearchResultData
+		if err := rows.Scan(&result.ID, &result.Sender, &result.Kind, &result.TargetPostID, &result.Timestamp,
+			&result.BlockIndex, &result.Snippet, &result.Rank); err != nil {
+			return nil, err
+		}
+		result.Snippet = markSnippet(result.Snippet, query.Terms)
+		results = append(results, result)
+	}
+	return results, rows.Err()
+}
+
+// markSnippet 截取内容中第一个匹配词前后 snippetContext 个字符，并用 <mark></mark> 标出其中的匹配词，忽略大小写
+func markSnippet(message string, terms []string) string {
+	runes := []rune(message)
+	lower := make([]rune, len(runes))
+	for i, r := range runes {
+		lower[i] = unicode.ToLower(r)
+	}
+	needles := make([][]rune, 0, len(terms))
+	for _, term := range terms {
+		if needle := []rune(strings.ToLower(term)); len(needle) > 0 {
+			needles = append(needles, needle)
+		}
+	}
+	matchAt := func(i int) int {
+		for _, needle := range needles {
+			if i+len(needle) <= len(lower) && string(lower[i:i+len(needle)]) == string(needle) {
+				return len(needle)
+			}
+		}
+		return 0
+	}
+
+	first := 0
+	for first < len(runes) && matchAt(first) == 0 {
+		first++
+	}
+	if first == len(runes) {
+		first = 0
+	}
+	start := max(0, first-snippetContext)
+	end := min(len(runes), first+matchAt(first)+snippetContext)
+
+	var b strings.Builder
+	if start > 0 {
+		b.WriteString("…")
+	}
+	for i := start; i < end; {
+		if n := matchAt(i); n > 0 {
+			b.WriteString("<mark>" + string(runes[i:i+n]) + "</mark>")
+			i += n
+			end = max(end, min(len(runes), i))
+			continue
+		}
+		b.WriteRune(runes[i])
+		i++
+	}
+	if end < len(runes) {
+		b.WriteString("…")
+	}
+	return b.String()
+}
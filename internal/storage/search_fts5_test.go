@@ -0,0 +1,23 @@
+//go:build sqlite_fts5
+
+package storage
+
+import "testing"
+
+func TestMarkSnippet(t *testing.T) {
+	tests := []struct {
+		message string
+		terms   []string
+		want    string
+	}{
+		{"今天天气很好", []string{"天气"}, "今天<mark>天气</mark>很好"},
+		{"Go is GO", []string{"go"}, "<mark>Go</mark> is <mark>GO</mark>"},
+		{"这是一段很长很长很长很长很长很长的内容，关键词在中间出现：天气，然后还有很长很长很长很长很长的结尾", []string{"天气"},
+			"…长很长的内容，关键词在中间出现：<mark>天气</mark>，然后还有很长很长很长很长很长的…"},
+	}
+	for _, tt := range tests {
+		if got := markSnippet(tt.message, tt.terms); got != tt.want {
+			t.Errorf("markSnippet(%q, %q) = %q, want %q", tt.message, tt.terms, got, tt.want)
+		}
+	}
+}
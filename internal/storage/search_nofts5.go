@@ -0,0 +1,27 @@
+//go:build !sqlite_fts5
+
+package storage
+
+import (
+	"database/sql"
+)
+
+// syncSearchIndex 未启用 FTS5 时不维护全文索引
+func syncSearchIndex(db *sql.DB) error {
+	return nil
+}
+
+// indexSearchBlock 未启用 FTS5 时不更新全文索引，只标记它已经落后
+func indexSearchBlock(tx *sql.Tx, block *BlockData) error {
+	return invalidateSearchIndex(tx)
+}
+
+// rebuildSearchIndex 未启用 FTS5 时不重建全文索引，只标记它已经落后
+func rebuildSearchIndex(tx *sql.Tx) error {
+	return invalidateSearchIndex(tx)
+}
+
+// Search 未启用 FTS5 时全文搜索不可用
+func (db *Database) Search(query SearchQuery) ([]SearchResultData, error) {
+	return nil, ErrSearchUnavailable
+}
@@ -5,9 +5,15 @@ import sys
 from typing import List, Dict
 import json
 import binascii
+import datetime
 import ed25519
 from tqdm import tqdm
 
+# 交易签名格式，需与 internal/blockchain/encoding.go 中的 SignBytes 保持一致
+TRANSACTION_VERSION = 1
+TRANSACTION_SIGN_DOMAIN = "twichain/transaction"
+CHAIN_ID = "twichain"
+
 class ConsensusTest:
     def __init__(self):
         self.nodes = [
@@ -17,6 +23,7 @@ class ConsensusTest:
         ]
         self.processes: List[subprocess.Popen] = []
         self.base_url = "http://localhost:{}"
+        self.nonce = 0
 
     def start_nodes(self):
         """启动所有节点"""
@@ -38,7 +45,34 @@ class ConsensusTest:
             process.wait()
         print("All nodes stopped")
 
-    def sign_message(self, private_key: str, content: str) -> str:
+    @staticmethod
+    def sign_bytes(tx: Dict, timestamp_ns: int) -> bytes:
+        """构造交易签名覆盖的规范编码"""
+        def encode_string(value: str) -> bytes:
+            data = value.encode('utf-8')
+            return len(data).to_bytes(4, 'big') + data
+
+        return (
+            bytes([tx["version"]])
+            + encode_string(TRANSACTION_SIGN_DOMAIN)
+            + encode_string(CHAIN_ID)
+            + encode_string(tx["sender"])
+            + encode_string(tx["receiver"])
+            + encode_string(tx["kind"])
+            + encode_string(tx["message"])
+            + encode_string(tx["target_post_id"])
+            + timestamp_ns.to_bytes(8, 'big', signed=True)
+            + tx["nonce"].to_bytes(8, 'big')
+        )
+
+    @staticmethod
+    def format_timestamp(timestamp_ns: int) -> str:
+        """将 Unix 纳秒时间戳格式化为 RFC 3339，保留纳秒精度"""
+        seconds, nanos = divmod(timestamp_ns, 1_000_000_000)
+        base = datetime.datetime.fromtimestamp(seconds, tz=datetime.timezone.utc)
+        return base.strftime('%Y-%m-%dT%H:%M:%S') + f".{nanos:09d}Z"
+
+    def sign_message(self, private_key: str, content) -> str:
             """使用Ed25519私钥签名内容"""
             try:
                 # 解码私钥
@@ -46,7 +80,7 @@ class ConsensusTest:
                 # 创建签名对象
                 signer = ed25519.SigningKey(priv_key_bytes)
                 # 签名消息
-                message_bytes = content.encode('utf-8')
+                message_bytes = content.encode('utf-8') if isinstance(content, str) else content
                 signature = signer.sign(message_bytes)
                 # 返回十六进制编码的签名
                 return binascii.hexlify(signature).decode('ascii')
@@ -60,14 +94,23 @@ class ConsensusTest:
     def create_transaction(self, node_port: str, message: str) -> Dict:
         """在指定节点创建交易"""
         url = f"{self.base_url.format(node_port)}/transactions/new"
+        self.nonce += 1
+        timestamp_ns = time.time_ns()
         data = {
             "sender": "6adb5500f467f004523d0f9e37acbbdaffc033b5f98fcb6c97fb601060b68f90",
             "receiver": "69c5f684026e6bd3e2a8f175a892ca6858cb9936b3c525ce11b981f848a69fc2",
             "message": message,
-            "signature": self.sign_message("d24cb18f2225cdf48f17560d8803e5a4285a8c2b17dd94d6b942cb686ba6a92c6adb5500f467f004523d0f9e37acbbdaffc033b5f98fcb6c97fb601060b68f90", message),  # 简化测试，实际应使用有效签名
             "is_like": False,
-            "target_post_id": ""
+            "kind": "post",
+            "target_post_id": "",
+            "timestamp": self.format_timestamp(timestamp_ns),
+            "nonce": self.nonce,
+            "version": TRANSACTION_VERSION
         }
+        data["signature"] = self.sign_message(
+            "d24cb18f2225cdf48f17560d8803e5a4285a8c2b17dd94d6b942cb686ba6a92c6adb5500f467f004523d0f9e37acbbdaffc033b5f98fcb6c97fb601060b68f90",
+            self.sign_bytes(data, timestamp_ns)
+        )
         
         try:
             response = requests.post(url, json=data)
@@ -76,6 +119,12 @@ class ConsensusTest:
             print(f"Error creating transaction on port {node_port}: {e}")
             return None
 
+    def sync_nonce(self, node_port: str, address: str):
+        """从节点获取发送者的下一个序号"""
+        url = f"{self.base_url.format(node_port)}/accounts/nonce"
+        response = requests.get(url, params={"address": address})
+        self.nonce = response.json()["next_nonce"] - 1
+
     def get_chain(self, node_port: str) -> Dict:
         """获取指定节点的区块链"""
         url = f"{self.base_url.format(node_port)}/chain"
@@ -114,6 +163,7 @@ class ConsensusTest:
             self.start_nodes()
             
             print("\nTesting consensus mechanism...")
+            self.sync_nonce(self.nodes[0]['port'], "6adb5500f467f004523d0f9e37acbbdaffc033b5f98fcb6c97fb601060b68f90")
             
             # 在不同节点创建交易
             print("\nCreating transactions on different nodes...")